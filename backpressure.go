@@ -0,0 +1,68 @@
+package eventbus
+
+// BackpressurePolicy defines what happens to a message published asynchronously
+// when the channel of its topic, or of a pipe, is full.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock blocks the publisher until there is room in the channel.
+	// This is the default policy.
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDropNewest drops the message being published.
+	BackpressureDropNewest
+	// BackpressureDropOldest drops the oldest message waiting in the channel to make room
+	// for the message being published. It behaves like BackpressureDropNewest for unbuffered channels.
+	BackpressureDropOldest
+	// BackpressureError drops the message being published and returns ErrQueueFull.
+	BackpressureError
+)
+
+// WithBackpressure sets what happens to the messages published asynchronously when the channel is full.
+// For unbuffered channels, the channel is full while the handlers are busy.
+func WithBackpressure(policy BackpressurePolicy) Option {
+	return func(o *options) {
+		o.backpressure = policy
+	}
+}
+
+// enqueue sends m to ch according to the backpressure policy.
+// drop is called with every message discarded to make room, or instead of sending m.
+// Returns ErrQueueFull if the policy is BackpressureError and the channel is full.
+func enqueue[M any](ch chan M, m M, policy BackpressurePolicy, drop func(M)) error {
+	switch policy {
+	case BackpressureDropNewest:
+		select {
+		case ch <- m:
+		default:
+			drop(m)
+		}
+		return nil
+	case BackpressureError:
+		select {
+		case ch <- m:
+			return nil
+		default:
+			drop(m)
+			return ErrQueueFull
+		}
+	case BackpressureDropOldest:
+		for {
+			select {
+			case ch <- m:
+				return nil
+			default:
+			}
+			if cap(ch) == 0 {
+				drop(m)
+				return nil
+			}
+			select {
+			case old := <-ch:
+				drop(old)
+			default:
+			}
+		}
+	}
+	ch <- m
+	return nil
+}
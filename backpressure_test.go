@@ -0,0 +1,70 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Enqueue(t *testing.T) {
+	var dropped []int
+	drop := func(val int) {
+		dropped = append(dropped, val)
+	}
+
+	ch := make(chan int, 2)
+	err := enqueue(ch, 1, BackpressureBlock, drop)
+	assert.Nil(t, err)
+	err = enqueue(ch, 2, BackpressureBlock, drop)
+	assert.Nil(t, err)
+
+	err = enqueue(ch, 3, BackpressureDropNewest, drop)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3}, dropped)
+
+	err = enqueue(ch, 4, BackpressureError, drop)
+	assert.Equal(t, ErrQueueFull, err)
+	assert.Equal(t, []int{3, 4}, dropped)
+
+	err = enqueue(ch, 5, BackpressureDropOldest, drop)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4, 1}, dropped)
+	assert.Equal(t, 2, <-ch)
+	assert.Equal(t, 5, <-ch)
+
+	unbuffered := make(chan int)
+	err = enqueue(unbuffered, 6, BackpressureDropOldest, drop)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4, 1, 6}, dropped)
+}
+
+func Test_WithBackpressure(t *testing.T) {
+	bus := NewWithOptions(WithBufferSize(1), WithBackpressure(BackpressureError))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		if val == 1 {
+			close(started)
+			<-release
+		}
+	})
+	assert.Nil(t, err)
+
+	err = bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	<-started
+	err = bus.Publish("testtopic", 2)
+	assert.Nil(t, err)
+	err = bus.Publish("testtopic", 3)
+	assert.Equal(t, ErrQueueFull, err)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return bus.QueueDepth("testtopic") == 0
+	}, time.Second, time.Millisecond)
+	stats := bus.Stats()["testtopic"]
+	assert.Equal(t, uint64(3), stats.Published)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	bus.Close()
+}
@@ -0,0 +1,213 @@
+package eventbus
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBatchSize is the default maximum number of messages of a batch.
+	defaultBatchSize = 100
+	// defaultBatchWait is the default maximum time a message waits in a batch.
+	defaultBatchWait = time.Second
+)
+
+// BatchOption configures a handler subscribed with SubscribeBatch().
+type BatchOption func(*batchOptions)
+
+// batchOptions holds the limits of the batches of a handler.
+type batchOptions struct {
+	maxSize int
+	maxWait time.Duration
+}
+
+// MaxSize delivers a batch as soon as it holds n messages. The default size is 100.
+func MaxSize(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.maxSize = n
+	}
+}
+
+// MaxWait delivers a batch at most d after its first message has been published, even if it is not full.
+// The default wait is one second. Zero or a negative duration means waiting until the batch is full.
+func MaxWait(d time.Duration) BatchOption {
+	return func(o *batchOptions) {
+		o.maxWait = d
+	}
+}
+
+// SubscribeBatch subscribes a handler receiving the messages of a topic in batches, like func(topic string, batch []T).
+// The messages are accumulated and delivered as a slice once the batch holds MaxSize() messages,
+// or once MaxWait() has elapsed since the first message of the batch, whichever comes first.
+// The batch in progress is delivered when the handler is unsubscribed and when the topic is closed.
+// The handler may have a leading context.Context parameter, which receives context.Background(),
+// and return an error like the handlers of Subscribe(). The messages whose payload is not a T are dropped
+// with ErrPayloadType. The calls of the handler are serialized.
+// Returns ErrHandlerBatchParam if the second parameter of the handler is not a slice.
+func (e *EventBus) SubscribeBatch(topic string, handler any, opts ...BatchOption) error {
+	h, err := newHandler(reflect.ValueOf(handler))
+	if err != nil {
+		return err
+	}
+	if h.envelope || h.delivery || h.fn.Type().In(h.fn.Type().NumIn()-1).Kind() != reflect.Slice {
+		return ErrHandlerBatchParam
+	}
+
+	o := batchOptions{maxSize: defaultBatchSize, maxWait: defaultBatchWait}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxSize <= 0 {
+		o.maxSize = defaultBatchSize
+	}
+
+	return e.withChannel(topic, func(ch *channel) error {
+		return ch.subscribeBatch(h, o)
+	})
+}
+
+// batcher accumulates the messages of a topic for a batch handler.
+type batcher struct {
+	c    *channel
+	h    *handler
+	opts batchOptions
+	// typ is the type of the batches, []T.
+	typ reflect.Type
+
+	// callMu serializes the calls of the handler.
+	callMu sync.Mutex
+	mu     sync.Mutex
+	batch  []*message
+	// gen identifies the current batch, so that the timer of a batch already delivered is ignored.
+	gen uint64
+}
+
+// subscribeBatch adds a batch handler to the channel.
+func (c *channel) subscribeBatch(h *handler, opts batchOptions) error {
+	c.RLock()
+	defer c.RUnlock()
+	if c.closed {
+		return ErrChannelClosed
+	}
+	c.touch()
+	if c.durable != nil {
+		defer c.durable.signal()
+	}
+	h.batcher = &batcher{
+		c:    c,
+		h:    h,
+		opts: opts,
+		typ:  h.fn.Type().In(h.fn.Type().NumIn() - 1),
+	}
+	if old, loaded := c.handlers.Swap(h.fn.Pointer(), h); loaded {
+		flushRemoved(old)
+	}
+	return nil
+}
+
+// flushRemoved delivers the batch in progress of a handler removed from a channel, if any.
+func flushRemoved(h any) {
+	if b := h.(*handler).batcher; b != nil {
+		b.flush()
+	}
+}
+
+// batchers returns the batchers of the handlers of the channel.
+func (c *channel) batchers() []*batcher {
+	var batchers []*batcher
+	c.handlers.Range(func(key any, h any) bool {
+		if b := h.(*handler).batcher; b != nil {
+			batchers = append(batchers, b)
+		}
+		return true
+	})
+	return batchers
+}
+
+// add adds a message to the batch, and delivers the batch if it is full.
+func (b *batcher) add(m *message) {
+	if m.payload != nil && !reflect.TypeOf(m.payload).AssignableTo(b.typ.Elem()) {
+		b.c.drop(m.payload, ErrPayloadType)
+		return
+	}
+
+	b.mu.Lock()
+	b.batch = append(b.batch, m)
+	full := len(b.batch) >= b.opts.maxSize
+	if len(b.batch) == 1 && !full && b.opts.maxWait > 0 {
+		gen := b.gen
+		time.AfterFunc(b.opts.maxWait, func() {
+			b.expire(gen)
+		})
+	}
+	b.mu.Unlock()
+	if full {
+		b.flush()
+	}
+}
+
+// flush delivers the batch in progress, if any.
+func (b *batcher) flush() {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	b.deliver(batch)
+}
+
+// expire delivers the batch gen once MaxWait has elapsed, unless it has already been delivered.
+func (b *batcher) expire(gen uint64) {
+	c := b.c
+	// Once the goroutines of the channel have exited, the batch has been flushed.
+	if !c.enter() {
+		return
+	}
+	defer c.exit()
+
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	b.mu.Lock()
+	if gen != b.gen {
+		b.mu.Unlock()
+		return
+	}
+	batch := b.take()
+	b.mu.Unlock()
+	b.deliver(batch)
+}
+
+// take removes the messages of the batch, and starts a new batch. The caller must hold b.mu.
+func (b *batcher) take() []*message {
+	batch := b.batch
+	b.batch = nil
+	b.gen++
+	return batch
+}
+
+// deliver calls the handler with a batch of messages. The caller must hold b.callMu,
+// so that the batches are delivered in order.
+func (b *batcher) deliver(batch []*message) {
+	if len(batch) == 0 {
+		return
+	}
+	payloads := reflect.MakeSlice(b.typ, len(batch), len(batch))
+	for i, m := range batch {
+		if m.payload != nil {
+			payloads.Index(i).Set(reflect.ValueOf(m.payload))
+		}
+	}
+	last := batch[len(batch)-1]
+	m := &message{
+		id:      last.id,
+		time:    last.time,
+		topic:   last.topic,
+		seq:     last.seq,
+		source:  last.source,
+		payload: payloads.Interface(),
+	}
+
+	b.c.call(context.Background(), b.h, m)
+}
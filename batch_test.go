@@ -0,0 +1,116 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// batchRecorder records the batches received by a handler.
+type batchRecorder struct {
+	sync.Mutex
+	batches [][]int
+}
+
+func (r *batchRecorder) handle(topic string, batch []int) {
+	r.Lock()
+	defer r.Unlock()
+	r.batches = append(r.batches, batch)
+}
+
+func (r *batchRecorder) get() [][]int {
+	r.Lock()
+	defer r.Unlock()
+	return append([][]int(nil), r.batches...)
+}
+
+func Test_SubscribeBatchErrors(t *testing.T) {
+	bus := New()
+	err := bus.SubscribeBatch("testtopic", 1)
+	assert.Equal(t, ErrHandlerIsNotFunc, err)
+	err = bus.SubscribeBatch("testtopic", busHandlerOne)
+	assert.Equal(t, ErrHandlerBatchParam, err)
+	err = bus.SubscribeBatch("testtopic", func(env *Envelope[[]int]) {})
+	assert.Equal(t, ErrHandlerBatchParam, err)
+	err = bus.SubscribeBatch("testtopic", func(ctx context.Context, topic string, batch []int) error { return nil })
+	assert.Nil(t, err)
+	bus.Close()
+}
+
+func Test_SubscribeBatchMaxSize(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	recorder := &batchRecorder{}
+	err := bus.SubscribeBatch("testtopic", recorder.handle, MaxSize(3), MaxWait(0))
+	assert.Nil(t, err)
+
+	for i := 1; i <= 7; i++ {
+		assert.Nil(t, bus.PublishSync("testtopic", i))
+	}
+	assert.Nil(t, bus.PublishSync("testtopic", "invalid"))
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}}, recorder.get())
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrPayloadType.Error())
+
+	// The batch in progress is delivered on close.
+	bus.Close()
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7}}, recorder.get())
+}
+
+func Test_SubscribeBatchMaxWait(t *testing.T) {
+	bus := New()
+	recorder := &batchRecorder{}
+	err := bus.SubscribeBatch("testtopic", recorder.handle, MaxSize(100), MaxWait(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	assert.Nil(t, bus.PublishSync("testtopic", 1))
+	assert.Nil(t, bus.PublishSync("testtopic", 2))
+	assert.Empty(t, recorder.get())
+	assert.Eventually(t, func() bool { return len(recorder.get()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, [][]int{{1, 2}}, recorder.get())
+
+	assert.Nil(t, bus.PublishSync("testtopic", 3))
+	assert.Eventually(t, func() bool { return len(recorder.get()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, [][]int{{1, 2}, {3}}, recorder.get())
+	bus.Close()
+	assert.Equal(t, 2, len(recorder.get()))
+}
+
+func Test_SubscribeBatchUnsubscribe(t *testing.T) {
+	bus := New()
+	recorder := &batchRecorder{}
+	err := bus.SubscribeBatch("testtopic", recorder.handle, MaxWait(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, bus.SubscriberCount("testtopic"))
+
+	assert.Nil(t, bus.PublishSync("testtopic", 1))
+	assert.Nil(t, bus.Unsubscribe("testtopic", recorder.handle))
+	assert.Equal(t, [][]int{{1}}, recorder.get())
+	bus.Close()
+	assert.Equal(t, [][]int{{1}}, recorder.get())
+}
+
+func Test_SubscribeBatchAsync(t *testing.T) {
+	bus := NewWithOptions(WithWorkers(4), WithBufferSize(100))
+	recorder := &batchRecorder{}
+	err := bus.SubscribeBatch("testtopic", recorder.handle, MaxSize(10), MaxWait(5*time.Millisecond))
+	assert.Nil(t, err)
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, bus.Publish("testtopic", i))
+	}
+
+	received := make(map[int]bool)
+	assert.Eventually(t, func() bool {
+		clear(received)
+		for _, batch := range recorder.get() {
+			assert.LessOrEqual(t, len(batch), 10)
+			for _, v := range batch {
+				received[v] = true
+			}
+		}
+		return len(received) == 1000
+	}, time.Second, time.Millisecond)
+	bus.Close()
+}
@@ -0,0 +1,310 @@
+// Package bridge shares the topics of eventbuses running in different processes over TCP,
+// or over Unix domain sockets for the processes running on the same host.
+//
+// A Server exposes an EventBus to the network, and a Client connected to it mirrors selected topics
+// between the remote eventbus and a local one: Import() publishes the messages of a remote topic
+// to the local eventbus, and Export() publishes the messages of a local topic to the remote eventbus.
+// The messages keep their ID, time, source and headers, and their payloads are encoded with a Codec,
+// JSONCodec by default. Since the wire carries no type information, the type of the payloads of a topic
+// must be declared with Topic() on the side receiving them, otherwise they are decoded as an any.
+// On Linux, the processes connecting over Unix domain sockets can be authorized from their
+// credentials with WithPeerAuthorizer().
+package bridge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"sync"
+
+	"github.com/werbenhu/eventbus"
+)
+
+const (
+	// OriginHeader is the header set on the messages published by a bridge to its local eventbus.
+	// It identifies the connection the message came from, so that the message is not sent back to it.
+	OriginHeader = "eventbus-bridge-origin"
+	// defaultQueueSize is the default number of frames waiting to be sent on a connection.
+	defaultQueueSize = 1024
+)
+
+// Option configures a Server or a Client.
+type Option func(*options)
+
+// options holds the settings of a Server or a Client.
+type options struct {
+	codec     eventbus.Codec
+	decoders  map[string]func(codec eventbus.Codec, data []byte) (any, error)
+	queueSize int
+	onError   func(topic string, err error)
+	authorize func(cred PeerCred) error
+}
+
+// WithCodec sets the Codec encoding the payloads of the messages. The default is JSONCodec.
+// Both ends of a connection must use the same codec.
+func WithCodec(codec eventbus.Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// Topic declares T as the type of the payloads of a topic, so that the messages of the topic
+// received from the network are decoded as a T. The payloads of the topics not declared are decoded as an any.
+func Topic[T any](topic string) Option {
+	return func(o *options) {
+		o.decoders[topic] = func(codec eventbus.Codec, data []byte) (any, error) {
+			var payload T
+			err := codec.Unmarshal(data, &payload)
+			return payload, err
+		}
+	}
+}
+
+// WithQueueSize sets the number of messages waiting to be sent on a connection, 1024 by default.
+// The handlers forwarding the messages block while the queue is full.
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithErrorHandler sets a function called with the messages which could not be forwarded,
+// because their payload could not be encoded or decoded, or because publishing them failed.
+// It is also called with an empty topic for the connections rejected by the peer authorizer.
+func WithErrorHandler(fn func(topic string, err error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// newOptions returns the options with the defaults applied.
+func newOptions(opts []Option) *options {
+	o := &options{
+		codec:     eventbus.JSONCodec{},
+		decoders:  make(map[string]func(codec eventbus.Codec, data []byte) (any, error)),
+		queueSize: defaultQueueSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.queueSize <= 0 {
+		o.queueSize = defaultQueueSize
+	}
+	return o
+}
+
+// report calls the error handler, if any.
+func (o *options) report(topic string, err error) {
+	if o.onError != nil {
+		o.onError(topic, err)
+	}
+}
+
+// link is a connection between a local eventbus and a remote one.
+type link struct {
+	// id identifies the link in the OriginHeader of the messages it publishes.
+	id   string
+	conn net.Conn
+	bus  *eventbus.EventBus
+	opts *options
+	// out holds the frames waiting to be sent by the writer goroutine.
+	out chan []byte
+
+	mu sync.Mutex
+	// exported holds the local topics forwarded to the remote eventbus.
+	exported map[string]struct{}
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	err       error
+	writerWg  sync.WaitGroup
+}
+
+// newLink creates a link over a connection and starts its writer goroutine.
+func newLink(conn net.Conn, bus *eventbus.EventBus, opts *options) *link {
+	id := make([]byte, 8)
+	rand.Read(id)
+	l := &link{
+		id:       hex.EncodeToString(id),
+		conn:     conn,
+		bus:      bus,
+		opts:     opts,
+		out:      make(chan []byte, opts.queueSize),
+		exported: make(map[string]struct{}),
+		closeCh:  make(chan struct{}),
+	}
+	l.writerWg.Add(1)
+	go l.writeLoop()
+	return l
+}
+
+// group is the name of the consumer group of the link, a group of its own so that it receives every message.
+func (l *link) group() string {
+	return "bridge-" + l.id
+}
+
+// send queues a frame to be sent. It blocks while the queue is full,
+// and returns ErrBridgeClosed if the link is closed.
+func (l *link) send(f *frame) error {
+	buf := f.marshal()
+	select {
+	case <-l.closeCh:
+		return ErrBridgeClosed
+	default:
+	}
+	select {
+	case l.out <- buf:
+		return nil
+	case <-l.closeCh:
+		return ErrBridgeClosed
+	}
+}
+
+// writeLoop sends the queued frames until the link is closed.
+func (l *link) writeLoop() {
+	defer l.writerWg.Done()
+	for {
+		select {
+		case buf := <-l.out:
+			if _, err := l.conn.Write(buf); err != nil {
+				l.close(err)
+				return
+			}
+		case <-l.closeCh:
+			return
+		}
+	}
+}
+
+// readLoop reads the frames of the connection and passes them to handle, until the link is closed
+// or handle returns an error. It then closes the link.
+func (l *link) readLoop(handle func(f *frame) error) {
+	for {
+		f, err := readFrame(l.conn)
+		if err == nil {
+			err = handle(f)
+		}
+		if err != nil {
+			l.close(err)
+			return
+		}
+	}
+}
+
+// close closes the link with the error which ended it, and removes its handlers from the local eventbus.
+// Only the first error is recorded.
+func (l *link) close(err error) {
+	l.closeOnce.Do(func() {
+		l.err = err
+		close(l.closeCh)
+		l.conn.Close()
+
+		l.mu.Lock()
+		exported := l.exported
+		l.exported = nil
+		l.mu.Unlock()
+		for topic := range exported {
+			l.bus.UnsubscribeGroup(topic, l.group(), l.forward)
+		}
+	})
+}
+
+// export forwards the messages published to a local topic to the remote eventbus.
+func (l *link) export(topic string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exported == nil {
+		return ErrBridgeClosed
+	}
+	if _, ok := l.exported[topic]; ok {
+		return nil
+	}
+	if err := l.bus.SubscribeGroup(topic, l.group(), l.forward); err != nil {
+		return err
+	}
+	l.exported[topic] = struct{}{}
+	return nil
+}
+
+// unexport stops forwarding the messages of a local topic to the remote eventbus.
+func (l *link) unexport(topic string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exported == nil {
+		return ErrBridgeClosed
+	}
+	if _, ok := l.exported[topic]; !ok {
+		return nil
+	}
+	delete(l.exported, topic)
+	return l.bus.UnsubscribeGroup(topic, l.group(), l.forward)
+}
+
+// forward is the handler sending the messages of the exported topics to the remote eventbus.
+// The messages received from the remote eventbus by this link are not sent back.
+func (l *link) forward(env *eventbus.Envelope[any]) error {
+	if env.Headers[OriginHeader] == l.id {
+		return nil
+	}
+	payload, err := l.opts.codec.Marshal(env.Payload)
+	if err != nil {
+		l.opts.report(env.Topic, err)
+		return err
+	}
+	var headers map[string]string
+	for k, v := range env.Headers {
+		if k == OriginHeader {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string, len(env.Headers))
+		}
+		headers[k] = v
+	}
+	return l.send(&frame{
+		typ:     framePublish,
+		topic:   env.Topic,
+		id:      env.ID,
+		time:    env.Time,
+		source:  env.Source,
+		headers: headers,
+		payload: payload,
+	})
+}
+
+// publish publishes a message received from the remote eventbus to the local eventbus.
+// The messages which can't be decoded or published are reported to the error handler and skipped.
+func (l *link) publish(f *frame) {
+	decode, ok := l.opts.decoders[f.topic]
+	if !ok {
+		decode = decodeAny
+	}
+	payload, err := decode(l.opts.codec, f.payload)
+	if err != nil {
+		l.opts.report(f.topic, err)
+		return
+	}
+	headers := make(map[string]string, len(f.headers)+1)
+	for k, v := range f.headers {
+		headers[k] = v
+	}
+	headers[OriginHeader] = l.id
+	err = l.bus.PublishEnvelope(&eventbus.Envelope[any]{
+		ID:      f.id,
+		Time:    f.time,
+		Topic:   f.topic,
+		Headers: headers,
+		Source:  f.source,
+		Payload: payload,
+	})
+	if err != nil {
+		l.opts.report(f.topic, err)
+	}
+}
+
+// decodeAny decodes the payloads of the topics whose type has not been declared with Topic().
+func decodeAny(codec eventbus.Codec, data []byte) (any, error) {
+	var payload any
+	err := codec.Unmarshal(data, &payload)
+	return payload, err
+}
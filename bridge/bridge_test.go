@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/eventbus"
+)
+
+// event is the payload of the messages of the tests.
+type event struct {
+	Name  string
+	Value int
+}
+
+// recorder records the envelopes received by a handler.
+type recorder struct {
+	sync.Mutex
+	received []*eventbus.Envelope[event]
+}
+
+func (r *recorder) handle(env *eventbus.Envelope[event]) {
+	r.Lock()
+	defer r.Unlock()
+	r.received = append(r.received, env)
+}
+
+func (r *recorder) envelopes() []*eventbus.Envelope[event] {
+	r.Lock()
+	defer r.Unlock()
+	return append([]*eventbus.Envelope[event](nil), r.received...)
+}
+
+func (r *recorder) len() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.received)
+}
+
+// startServer starts a server on a loopback listener, and returns its address.
+func startServer(t *testing.T, bus *eventbus.EventBus, opts ...Option) (*Server, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := NewServer(bus, opts...)
+	go s.Serve(ln)
+	return s, ln.Addr().String()
+}
+
+func Test_NewOptions(t *testing.T) {
+	o := newOptions(nil)
+	assert.Equal(t, eventbus.JSONCodec{}, o.codec)
+	assert.Equal(t, defaultQueueSize, o.queueSize)
+	assert.Empty(t, o.decoders)
+	o.report("testtopic", ErrInvalidFrame)
+
+	var reported []string
+	o = newOptions([]Option{
+		WithQueueSize(-1),
+		Topic[event]("testtopic"),
+		WithErrorHandler(func(topic string, err error) {
+			reported = append(reported, topic+":"+err.Error())
+		}),
+	})
+	assert.Equal(t, defaultQueueSize, o.queueSize)
+	payload, err := o.decoders["testtopic"](o.codec, []byte(`{"Name":"a","Value":1}`))
+	assert.Nil(t, err)
+	assert.Equal(t, event{Name: "a", Value: 1}, payload)
+	payload, err = decodeAny(o.codec, []byte(`{"Name":"a","Value":1}`))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"Name": "a", "Value": float64(1)}, payload)
+
+	o.report("testtopic", ErrInvalidFrame)
+	assert.Equal(t, []string{"testtopic:" + ErrInvalidFrame.Error()}, reported)
+}
@@ -0,0 +1,134 @@
+package bridge
+
+import (
+	"net"
+	"sync"
+
+	"github.com/werbenhu/eventbus"
+)
+
+// Client connects a local EventBus to the eventbus exposed by a Server, and mirrors selected topics between them.
+// The client does not reconnect: once the connection is lost, Done() is closed and Err() reports why.
+type Client struct {
+	l *link
+
+	mu sync.Mutex
+	// imported holds the remote topics forwarded to the local eventbus.
+	imported map[string]struct{}
+	done     chan struct{}
+}
+
+// Dial connects to a server listening on a TCP address, see NewClient().
+// Since the credentials of a TCP peer are not available, Dial() fails with ErrPeerCredentials
+// if a peer authorizer is set with WithPeerAuthorizer().
+func Dial(addr string, bus *eventbus.EventBus, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.authorizePeer(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newClient(conn, bus, o), nil
+}
+
+// NewClient creates a client connecting a local eventbus to a server over an established connection.
+// The client takes the ownership of the connection. It does not close the eventbus.
+// The peer authorizer set with WithPeerAuthorizer() is not applied, see Dial() and DialUnix().
+func NewClient(conn net.Conn, bus *eventbus.EventBus, opts ...Option) *Client {
+	return newClient(conn, bus, newOptions(opts))
+}
+
+// newClient creates a client with the given options.
+func newClient(conn net.Conn, bus *eventbus.EventBus, opts *options) *Client {
+	c := &Client{
+		l:        newLink(conn, bus, opts),
+		imported: make(map[string]struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		c.l.readLoop(func(f *frame) error {
+			if f.typ != framePublish {
+				return ErrInvalidFrame
+			}
+			c.l.publish(f)
+			return nil
+		})
+		c.l.writerWg.Wait()
+		close(c.done)
+	}()
+	return c
+}
+
+// Import publishes the messages of a remote topic to the local eventbus, from now on.
+func (c *Client) Import(topic string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.imported[topic]; ok {
+		return nil
+	}
+	if err := c.l.send(&frame{typ: frameSubscribe, topic: topic}); err != nil {
+		return err
+	}
+	c.imported[topic] = struct{}{}
+	return nil
+}
+
+// StopImport stops publishing the messages of a remote topic to the local eventbus.
+// The messages already sent by the server may still be published.
+func (c *Client) StopImport(topic string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.imported[topic]; !ok {
+		return nil
+	}
+	if err := c.l.send(&frame{typ: frameUnsubscribe, topic: topic}); err != nil {
+		return err
+	}
+	delete(c.imported, topic)
+	return nil
+}
+
+// Export publishes the messages of a local topic to the remote eventbus.
+// The messages imported from the remote eventbus are not sent back to it.
+func (c *Client) Export(topic string) error {
+	return c.l.export(topic)
+}
+
+// StopExport stops publishing the messages of a local topic to the remote eventbus.
+func (c *Client) StopExport(topic string) error {
+	return c.l.unexport(topic)
+}
+
+// Mirror imports and exports a topic, so that the local and the remote eventbuses share its messages.
+func (c *Client) Mirror(topic string) error {
+	if err := c.Import(topic); err != nil {
+		return err
+	}
+	return c.Export(topic)
+}
+
+// Close closes the connection and waits for the goroutines of the client to exit.
+func (c *Client) Close() error {
+	c.l.close(ErrBridgeClosed)
+	<-c.done
+	return nil
+}
+
+// Done returns a channel closed once the connection is closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error which closed the connection once Done() is closed: ErrBridgeClosed if the client
+// has been closed, io.EOF if the server closed the connection, or the error of the connection.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.l.err
+	default:
+		return nil
+	}
+}
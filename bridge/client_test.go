@@ -0,0 +1,137 @@
+package bridge
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/eventbus"
+)
+
+func Test_ClientImportExport(t *testing.T) {
+	remote := eventbus.New()
+	defer remote.Close()
+	s, addr := startServer(t, remote, Topic[event]("up"))
+	defer s.Close()
+
+	local := eventbus.New()
+	defer local.Close()
+	c, err := Dial(addr, local, Topic[event]("down"))
+	assert.Nil(t, err)
+	defer c.Close()
+
+	down := &recorder{}
+	assert.Nil(t, local.Subscribe("down", down.handle))
+	assert.Nil(t, c.Import("down"))
+	assert.Nil(t, c.Import("down"))
+	assert.Eventually(t, func() bool { return remote.SubscriberCount("down") == 1 }, time.Second, time.Millisecond)
+	err = remote.PublishEnvelopeSync(&eventbus.Envelope[event]{
+		Topic:   "down",
+		ID:      "id-1",
+		Source:  "remote",
+		Headers: map[string]string{"key": "value"},
+		Payload: event{Name: "down", Value: 1},
+	})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return down.len() == 1 }, time.Second, time.Millisecond)
+	env := down.envelopes()[0]
+	assert.Equal(t, "id-1", env.ID)
+	assert.Equal(t, "remote", env.Source)
+	assert.Equal(t, "value", env.Headers["key"])
+	assert.Equal(t, event{Name: "down", Value: 1}, env.Payload)
+
+	up := &recorder{}
+	assert.Nil(t, remote.Subscribe("up", up.handle))
+	assert.Nil(t, c.Export("up"))
+	assert.Nil(t, local.PublishSync("up", event{Name: "up", Value: 2}))
+	assert.Eventually(t, func() bool { return up.len() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, event{Name: "up", Value: 2}, up.envelopes()[0].Payload)
+
+	assert.Nil(t, c.StopImport("down"))
+	assert.Nil(t, c.StopExport("up"))
+	assert.Eventually(t, func() bool { return remote.SubscriberCount("down") == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 0, local.SubscriberCount("up"))
+	assert.Nil(t, remote.PublishSync("down", event{Name: "down", Value: 3}))
+	assert.Nil(t, local.PublishSync("up", event{Name: "up", Value: 4}))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, down.len())
+	assert.Equal(t, 1, up.len())
+}
+
+func Test_ClientMirror(t *testing.T) {
+	server := eventbus.New()
+	defer server.Close()
+	s, addr := startServer(t, server, Topic[event]("testtopic"))
+	defer s.Close()
+
+	buses := make([]*eventbus.EventBus, 2)
+	recorders := make([]*recorder, 2)
+	for i := range buses {
+		buses[i] = eventbus.New()
+		defer buses[i].Close()
+		recorders[i] = &recorder{}
+		assert.Nil(t, buses[i].Subscribe("testtopic", recorders[i].handle))
+		c, err := Dial(addr, buses[i], Topic[event]("testtopic"))
+		assert.Nil(t, err)
+		defer c.Close()
+		assert.Nil(t, c.Mirror("testtopic"))
+	}
+	serverRecorder := &recorder{}
+	assert.Nil(t, server.Subscribe("testtopic", serverRecorder.handle))
+	assert.Eventually(t, func() bool { return server.SubscriberCount("testtopic") == 3 }, time.Second, time.Millisecond)
+
+	// Every message reaches every eventbus exactly once.
+	assert.Nil(t, buses[0].PublishSync("testtopic", event{Name: "first", Value: 1}))
+	assert.Nil(t, server.PublishSync("testtopic", event{Name: "server", Value: 2}))
+	assert.Nil(t, buses[1].PublishSync("testtopic", event{Name: "second", Value: 3}))
+	for _, r := range append(recorders, serverRecorder) {
+		assert.Eventually(t, func() bool { return r.len() == 3 }, time.Second, time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	for _, r := range append(recorders, serverRecorder) {
+		assert.Equal(t, 3, r.len())
+	}
+}
+
+func Test_ClientErrors(t *testing.T) {
+	remote := eventbus.New()
+	defer remote.Close()
+	s, addr := startServer(t, remote)
+
+	reported := make(chan error, 1)
+	local := eventbus.New()
+	defer local.Close()
+	c, err := Dial(addr, local, Topic[event]("testtopic"), WithErrorHandler(func(topic string, err error) {
+		reported <- err
+	}))
+	assert.Nil(t, err)
+	assert.Nil(t, local.Subscribe("testtopic", func(topic string, e event) {}))
+	assert.Nil(t, c.Import("testtopic"))
+	assert.Eventually(t, func() bool { return remote.SubscriberCount("testtopic") == 1 }, time.Second, time.Millisecond)
+
+	// A payload which can't be decoded is reported and skipped.
+	assert.Nil(t, remote.PublishSync("testtopic", "invalid"))
+	select {
+	case err := <-reported:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the error has not been reported")
+	}
+	assert.Nil(t, c.Err())
+
+	// The client is closed when the server is closed.
+	assert.Nil(t, s.Close())
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the client has not been closed")
+	}
+	assert.Equal(t, io.EOF, c.Err())
+	assert.Equal(t, ErrBridgeClosed, c.Import("other"))
+	assert.Equal(t, ErrBridgeClosed, c.Export("other"))
+	assert.Nil(t, c.Close())
+
+	_, err = Dial("127.0.0.1:0", local)
+	assert.NotNil(t, err)
+}
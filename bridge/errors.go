@@ -0,0 +1,23 @@
+package bridge
+
+type err struct {
+	Msg  string
+	Code int
+}
+
+// String return the error's message
+func (e err) String() string {
+	return e.Msg
+}
+
+// Error return the error's message
+func (e err) Error() string {
+	return e.Msg
+}
+
+// Global variables that represent common errors that may be
+// returned by the bridge functions.
+var (
+	ErrInvalidFrame = err{Code: 20000, Msg: "invalid bridge frame"}
+	ErrBridgeClosed = err{Code: 20001, Msg: "bridge is closed"}
+)
@@ -0,0 +1,12 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrString(t *testing.T) {
+	require.Equal(t, ErrInvalidFrame.Msg, ErrInvalidFrame.String())
+	require.Equal(t, ErrBridgeClosed.Msg, error(ErrBridgeClosed).Error())
+}
@@ -0,0 +1,152 @@
+package bridge
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	// frameHeaderSize is the size of the header of a frame: the length of its body.
+	frameHeaderSize = 4
+	// maxFrameSize is the maximum size of the body of a frame, guarding against corrupted lengths.
+	maxFrameSize = 64 << 20
+)
+
+// frameType identifies the kind of a frame, it is the first byte of its body.
+type frameType byte
+
+const (
+	// frameSubscribe asks the server to forward the messages of a topic.
+	frameSubscribe frameType = iota + 1
+	// frameUnsubscribe asks the server to stop forwarding the messages of a topic.
+	frameUnsubscribe
+	// framePublish carries a message published to a topic.
+	framePublish
+)
+
+// frame is a unit of the wire protocol of the bridge.
+// A frame is sent as the length of its body as a big-endian uint32, followed by its body:
+// the type of the frame, then its fields, the strings being prefixed by their length as a uvarint.
+// Subscribe and unsubscribe frames only have a topic. Publish frames have the topic, the ID,
+// the time in nanoseconds since the epoch as a big-endian uint64, the source and the headers
+// of the message, the headers being prefixed by their count as a uvarint, followed by the payload
+// encoded with the Codec of the bridge, up to the end of the frame.
+type frame struct {
+	typ     frameType
+	topic   string
+	id      string
+	time    time.Time
+	source  string
+	headers map[string]string
+	payload []byte
+}
+
+// marshal returns the frame with its header.
+func (f *frame) marshal() []byte {
+	buf := make([]byte, frameHeaderSize, frameHeaderSize+32+len(f.topic)+len(f.id)+len(f.source)+len(f.payload))
+	buf = append(buf, byte(f.typ))
+	buf = appendString(buf, f.topic)
+	if f.typ == framePublish {
+		buf = appendString(buf, f.id)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(f.time.UnixNano()))
+		buf = appendString(buf, f.source)
+		buf = binary.AppendUvarint(buf, uint64(len(f.headers)))
+		for k, v := range f.headers {
+			buf = appendString(buf, k)
+			buf = appendString(buf, v)
+		}
+		buf = append(buf, f.payload...)
+	}
+	binary.BigEndian.PutUint32(buf[0:frameHeaderSize], uint32(len(buf)-frameHeaderSize))
+	return buf
+}
+
+// unmarshalFrame decodes the body of a frame.
+func unmarshalFrame(body []byte) (*frame, error) {
+	if len(body) < 1 {
+		return nil, ErrInvalidFrame
+	}
+	f := &frame{typ: frameType(body[0])}
+	var ok bool
+	var rest []byte
+	if f.topic, rest, ok = readString(body[1:]); !ok {
+		return nil, ErrInvalidFrame
+	}
+
+	switch f.typ {
+	case frameSubscribe, frameUnsubscribe:
+		if len(rest) != 0 {
+			return nil, ErrInvalidFrame
+		}
+		return f, nil
+	case framePublish:
+	default:
+		return nil, ErrInvalidFrame
+	}
+
+	if f.id, rest, ok = readString(rest); !ok || len(rest) < 8 {
+		return nil, ErrInvalidFrame
+	}
+	f.time = time.Unix(0, int64(binary.BigEndian.Uint64(rest[0:8])))
+	if f.source, rest, ok = readString(rest[8:]); !ok {
+		return nil, ErrInvalidFrame
+	}
+	n, size := binary.Uvarint(rest)
+	if size <= 0 || n > uint64(len(rest)) {
+		return nil, ErrInvalidFrame
+	}
+	rest = rest[size:]
+	if n > 0 {
+		f.headers = make(map[string]string, n)
+	}
+	for i := uint64(0); i < n; i++ {
+		var k, v string
+		if k, rest, ok = readString(rest); !ok {
+			return nil, ErrInvalidFrame
+		}
+		if v, rest, ok = readString(rest); !ok {
+			return nil, ErrInvalidFrame
+		}
+		f.headers[k] = v
+	}
+	f.payload = rest
+	return f, nil
+}
+
+// readFrame reads a frame from r. It returns ErrInvalidFrame if the frame is too large or invalid,
+// and the error of r if it fails, io.EOF if r is closed between two frames.
+func readFrame(r io.Reader) (*frame, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length > maxFrameSize {
+		return nil, ErrInvalidFrame
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return unmarshalFrame(body)
+}
+
+// appendString appends a length-prefixed string to buf.
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// readString reads a length-prefixed string from buf, and returns the rest of buf.
+func readString(buf []byte) (string, []byte, bool) {
+	n, size := binary.Uvarint(buf)
+	if size <= 0 || n > uint64(len(buf)-size) {
+		return "", nil, false
+	}
+	end := size + int(n)
+	return string(buf[size:end]), buf[end:], true
+}
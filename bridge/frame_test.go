@@ -0,0 +1,74 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FrameMarshal(t *testing.T) {
+	now := time.Unix(0, time.Now().UnixNano())
+	frames := []*frame{
+		{typ: frameSubscribe, topic: "testtopic"},
+		{typ: frameUnsubscribe, topic: ""},
+		{
+			typ:     framePublish,
+			topic:   "testtopic",
+			id:      "id-1",
+			time:    now,
+			source:  "source",
+			headers: map[string]string{"key": "value", "empty": ""},
+			payload: []byte(`{"a":1}`),
+		},
+		{typ: framePublish, topic: "testtopic", time: now, payload: []byte{}},
+	}
+
+	var buf bytes.Buffer
+	for _, f := range frames {
+		buf.Write(f.marshal())
+	}
+	for _, expected := range frames {
+		f, err := readFrame(&buf)
+		assert.Nil(t, err)
+		assert.Equal(t, expected.typ, f.typ)
+		assert.Equal(t, expected.topic, f.topic)
+		if expected.typ == framePublish {
+			assert.Equal(t, expected.id, f.id)
+			assert.True(t, expected.time.Equal(f.time))
+			assert.Equal(t, expected.source, f.source)
+			assert.Equal(t, expected.headers, f.headers)
+			assert.Equal(t, expected.payload, f.payload)
+		}
+	}
+	_, err := readFrame(&buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_ReadFrameErrors(t *testing.T) {
+	data := (&frame{typ: framePublish, topic: "testtopic", id: "id-1", payload: []byte("1")}).marshal()
+	_, err := readFrame(bytes.NewReader(data[:len(data)-1]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = readFrame(bytes.NewReader(data[:2]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	var header [frameHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], maxFrameSize+1)
+	_, err = readFrame(bytes.NewReader(header[:]))
+	assert.Equal(t, ErrInvalidFrame, err)
+
+	for _, body := range [][]byte{
+		{},
+		{byte(frameSubscribe)},
+		{byte(frameSubscribe), 1, 'a', 'b'},
+		{0xff, 1, 'a'},
+		{byte(framePublish), 1, 'a', 0},
+		{byte(framePublish), 1, 'a', 0, 0, 0, 0, 0, 0, 0, 0, 0, 5},
+	} {
+		_, err = unmarshalFrame(body)
+		assert.Equal(t, ErrInvalidFrame, err, "body %v", body)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package bridge
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCred returns the credentials of the peer of a Unix domain socket, read with SO_PEERCRED.
+// Returns ErrPeerCredentials for the other connections.
+func peerCred(conn net.Conn) (PeerCred, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return PeerCred{}, ErrPeerCredentials
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return PeerCred{}, err
+	}
+	var ucred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return PeerCred{}, err
+	}
+	if credErr != nil {
+		return PeerCred{}, credErr
+	}
+	return PeerCred{PID: int(ucred.Pid), UID: int(ucred.Uid), GID: int(ucred.Gid)}, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package bridge
+
+import (
+	"net"
+)
+
+// peerCred returns ErrPeerCredentials, since SO_PEERCRED is only supported on Linux.
+func peerCred(conn net.Conn) (PeerCred, error) {
+	return PeerCred{}, ErrPeerCredentials
+}
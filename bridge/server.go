@@ -0,0 +1,146 @@
+package bridge
+
+import (
+	"net"
+	"sync"
+
+	"github.com/werbenhu/eventbus"
+)
+
+// Server exposes an EventBus to the Clients connecting to it.
+// The clients subscribe to the topics of the eventbus, and publish messages to it.
+type Server struct {
+	bus  *eventbus.EventBus
+	opts *options
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	links     map[*link]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer creates a server exposing an eventbus. The server does not close the eventbus.
+func NewServer(bus *eventbus.EventBus, opts ...Option) *Server {
+	return &Server{
+		bus:       bus,
+		opts:      newOptions(opts),
+		listeners: make(map[net.Listener]struct{}),
+		links:     make(map[*link]struct{}),
+	}
+}
+
+// ListenAndServe listens on a TCP address and serves the connections, see Serve().
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts the connections of a listener and serves them until the server is closed.
+// It closes the listener, and returns ErrBridgeClosed once the server is closed,
+// or the error which made accepting a connection fail.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrBridgeClosed
+	}
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, ln)
+		s.mu.Unlock()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrBridgeClosed
+			}
+			return err
+		}
+		if !s.serveConn(conn) {
+			return ErrBridgeClosed
+		}
+	}
+}
+
+// serveConn starts serving a connection. It reports false, and closes the connection, if the server is closed.
+// The connections whose peer is not authorized are closed.
+func (s *Server) serveConn(conn net.Conn) bool {
+	if err := s.opts.authorizePeer(conn); err != nil {
+		conn.Close()
+		s.opts.report("", err)
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		conn.Close()
+		return false
+	}
+	l := newLink(conn, s.bus, s.opts)
+	s.links[l] = struct{}{}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		l.readLoop(func(f *frame) error {
+			switch f.typ {
+			case frameSubscribe:
+				return l.export(f.topic)
+			case frameUnsubscribe:
+				return l.unexport(f.topic)
+			default:
+				l.publish(f)
+				return nil
+			}
+		})
+		l.writerWg.Wait()
+		s.mu.Lock()
+		delete(s.links, l)
+		s.mu.Unlock()
+	}()
+	return true
+}
+
+// Close stops the listeners and closes the connections of the clients,
+// then waits for the goroutines serving them to exit.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	links := make([]*link, 0, len(s.links))
+	for l := range s.links {
+		links = append(links, l)
+	}
+	s.mu.Unlock()
+
+	for _, l := range links {
+		l.close(ErrBridgeClosed)
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// Clients returns the number of clients currently connected.
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.links)
+}
@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/eventbus"
+)
+
+func Test_ServerRawConnection(t *testing.T) {
+	bus := eventbus.New()
+	defer bus.Close()
+	s, addr := startServer(t, bus, Topic[event]("testtopic"))
+	defer s.Close()
+
+	r := &recorder{}
+	assert.Nil(t, bus.Subscribe("testtopic", r.handle))
+
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write((&frame{typ: frameSubscribe, topic: "testtopic"}).marshal())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return bus.SubscriberCount("testtopic") == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, s.Clients())
+
+	// The messages published by the client are published to the eventbus, but not sent back to the client.
+	_, err = conn.Write((&frame{
+		typ:     framePublish,
+		topic:   "testtopic",
+		id:      "id-1",
+		time:    time.Unix(1, 0),
+		payload: []byte(`{"Name":"remote","Value":1}`),
+	}).marshal())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return r.len() == 1 }, time.Second, time.Millisecond)
+	env := r.envelopes()[0]
+	assert.Equal(t, "id-1", env.ID)
+	assert.True(t, time.Unix(1, 0).Equal(env.Time))
+	assert.Equal(t, event{Name: "remote", Value: 1}, env.Payload)
+	assert.NotEmpty(t, env.Headers[OriginHeader])
+
+	// The messages published to the eventbus are sent to the client.
+	assert.Nil(t, bus.PublishSync("testtopic", event{Name: "local", Value: 2}))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	f, err := readFrame(conn)
+	assert.Nil(t, err)
+	assert.Equal(t, framePublish, f.typ)
+	assert.Equal(t, "testtopic", f.topic)
+	assert.JSONEq(t, `{"Name":"local","Value":2}`, string(f.payload))
+
+	_, err = conn.Write((&frame{typ: frameUnsubscribe, topic: "testtopic"}).marshal())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return bus.SubscriberCount("testtopic") == 1 }, time.Second, time.Millisecond)
+
+	// An invalid frame closes the connection.
+	_, err = conn.Write([]byte{0, 0, 0, 1, 0xff})
+	assert.Nil(t, err)
+	_, err = readFrame(conn)
+	assert.NotNil(t, err)
+	assert.Eventually(t, func() bool { return s.Clients() == 0 }, time.Second, time.Millisecond)
+}
+
+func Test_ServerClose(t *testing.T) {
+	bus := eventbus.New()
+	defer bus.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := NewServer(bus)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(ln)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write((&frame{typ: frameSubscribe, topic: "testtopic"}).marshal())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return bus.SubscriberCount("testtopic") == 1 }, time.Second, time.Millisecond)
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrBridgeClosed, <-errCh)
+	assert.Equal(t, 0, s.Clients())
+	assert.Equal(t, 0, bus.SubscriberCount("testtopic"))
+	_, err = readFrame(conn)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, s.Close())
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrBridgeClosed, s.Serve(ln))
+	assert.Equal(t, ErrBridgeClosed, s.ListenAndServe("127.0.0.1:0"))
+}
@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"net"
+	"os"
+
+	"github.com/werbenhu/eventbus"
+)
+
+// Errors returned when authorizing the peer of a connection.
+var (
+	ErrPeerCredentials  = err{Code: 20002, Msg: "peer credentials are not available"}
+	ErrPeerUnauthorized = err{Code: 20003, Msg: "peer is not authorized"}
+)
+
+// PeerCred holds the credentials of the process at the other end of a Unix domain socket,
+// as reported by the kernel when the connection was established.
+type PeerCred struct {
+	PID int
+	UID int
+	GID int
+}
+
+// WithPeerAuthorizer sets a function authorizing the processes at the other end of the connections,
+// from their credentials. A Server closes the connections whose peer is not authorized, and DialUnix()
+// returns the error of the authorizer. Credentials are only available for the Unix domain sockets on Linux,
+// the connections whose credentials can't be read are rejected with ErrPeerCredentials.
+func WithPeerAuthorizer(fn func(cred PeerCred) error) Option {
+	return func(o *options) {
+		o.authorize = fn
+	}
+}
+
+// AllowUIDs returns a peer authorizer accepting the processes running as one of the given users,
+// and rejecting the others with ErrPeerUnauthorized.
+func AllowUIDs(uids ...int) func(cred PeerCred) error {
+	return func(cred PeerCred) error {
+		for _, uid := range uids {
+			if cred.UID == uid {
+				return nil
+			}
+		}
+		return ErrPeerUnauthorized
+	}
+}
+
+// DialUnix connects to a server listening on a Unix domain socket, see NewClient().
+func DialUnix(path string, bus *eventbus.EventBus, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.authorizePeer(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newClient(conn, bus, o), nil
+}
+
+// ListenAndServeUnix listens on a Unix domain socket and serves the connections, see Serve().
+// A socket file left at path by a server which is no longer running is removed first.
+// The socket file is removed when the server is closed.
+func (s *Server) ListenAndServeUnix(path string) error {
+	removeStaleSocket(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// removeStaleSocket removes the socket file at path if no server accepts connections on it.
+func removeStaleSocket(path string) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return
+	}
+	os.Remove(path)
+}
+
+// authorizePeer checks the credentials of the peer of a connection with the authorizer, if any.
+func (o *options) authorizePeer(conn net.Conn) error {
+	if o.authorize == nil {
+		return nil
+	}
+	cred, err := peerCred(conn)
+	if err != nil {
+		return err
+	}
+	return o.authorize(cred)
+}
@@ -0,0 +1,146 @@
+//go:build linux
+
+package bridge
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/eventbus"
+)
+
+// startUnixServer starts a server on a Unix domain socket, and returns the path of the socket.
+func startUnixServer(t *testing.T, bus *eventbus.EventBus, opts ...Option) (*Server, string) {
+	path := filepath.Join(t.TempDir(), "bridge.sock")
+	s := NewServer(bus, opts...)
+	go s.ListenAndServeUnix(path)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, time.Millisecond)
+	return s, path
+}
+
+func Test_PeerCred(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peer.sock")
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	defer ln.Close()
+	conn, err := net.Dial("unix", path)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	cred, err := peerCred(conn)
+	assert.Nil(t, err)
+	assert.Equal(t, PeerCred{PID: os.Getpid(), UID: os.Getuid(), GID: os.Getgid()}, cred)
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer tcp.Close()
+	conn, err = net.Dial("tcp", tcp.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = peerCred(conn)
+	assert.Equal(t, ErrPeerCredentials, err)
+}
+
+func Test_AllowUIDs(t *testing.T) {
+	authorize := AllowUIDs(0, 1000)
+	assert.Nil(t, authorize(PeerCred{UID: 1000}))
+	assert.Equal(t, ErrPeerUnauthorized, authorize(PeerCred{UID: 1001}))
+	assert.Equal(t, ErrPeerUnauthorized, AllowUIDs()(PeerCred{}))
+}
+
+func Test_UnixMirror(t *testing.T) {
+	remote := eventbus.New()
+	defer remote.Close()
+	s, path := startUnixServer(t, remote, Topic[event]("testtopic"), WithPeerAuthorizer(AllowUIDs(os.Getuid())))
+	defer s.Close()
+
+	local := eventbus.New()
+	defer local.Close()
+	c, err := DialUnix(path, local, Topic[event]("testtopic"), WithPeerAuthorizer(AllowUIDs(os.Getuid())))
+	assert.Nil(t, err)
+	defer c.Close()
+	assert.Nil(t, c.Mirror("testtopic"))
+
+	localRecorder := &recorder{}
+	remoteRecorder := &recorder{}
+	assert.Nil(t, local.Subscribe("testtopic", localRecorder.handle))
+	assert.Nil(t, remote.Subscribe("testtopic", remoteRecorder.handle))
+	assert.Eventually(t, func() bool { return remote.SubscriberCount("testtopic") == 2 }, time.Second, time.Millisecond)
+
+	assert.Nil(t, local.PublishSync("testtopic", event{Name: "local", Value: 1}))
+	assert.Nil(t, remote.PublishSync("testtopic", event{Name: "remote", Value: 2}))
+	assert.Eventually(t, func() bool { return localRecorder.len() == 2 && remoteRecorder.len() == 2 }, time.Second, time.Millisecond)
+}
+
+func Test_UnixUnauthorized(t *testing.T) {
+	reported := make(chan error, 1)
+	remote := eventbus.New()
+	defer remote.Close()
+	s, path := startUnixServer(t, remote, WithPeerAuthorizer(AllowUIDs(os.Getuid()+1)), WithErrorHandler(func(topic string, err error) {
+		reported <- err
+	}))
+	defer s.Close()
+
+	local := eventbus.New()
+	defer local.Close()
+	c, err := DialUnix(path, local)
+	assert.Nil(t, err)
+	defer c.Close()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the connection has not been rejected")
+	}
+	assert.Equal(t, ErrPeerUnauthorized, <-reported)
+	assert.Equal(t, 0, s.Clients())
+
+	// The client rejects a server which is not authorized.
+	_, err = DialUnix(path, local, WithPeerAuthorizer(AllowUIDs(os.Getuid()+1)))
+	assert.Equal(t, ErrPeerUnauthorized, err)
+
+	// The credentials of a TCP peer are not available.
+	_, addr := startServer(t, remote)
+	_, err = Dial(addr, local, WithPeerAuthorizer(AllowUIDs(os.Getuid())))
+	assert.Equal(t, ErrPeerCredentials, err)
+}
+
+func Test_ListenAndServeUnixStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stale.sock")
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	// Leave the socket file behind, like a crashed server.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	bus := eventbus.New()
+	defer bus.Close()
+	s := NewServer(bus)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeUnix(path)
+	}()
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, time.Millisecond)
+
+	// A socket file of a running server is not removed.
+	other := NewServer(bus)
+	assert.NotNil(t, other.ListenAndServeUnix(path))
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrBridgeClosed, <-errCh)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,39 @@
+package eventbus
+
+import "encoding/json"
+
+// Codec encodes and decodes the payloads of the messages stored or transmitted by the eventbus.
+type Codec interface {
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes data into the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is a Codec using encoding/json. It is the default codec.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// WithCodec sets the Codec encoding the payloads of the durable topics. The default is JSONCodec.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// codecOrDefault returns the codec set with WithCodec(), or JSONCodec.
+func (o *options) codecOrDefault() Codec {
+	if o.codec == nil {
+		return JSONCodec{}
+	}
+	return o.codec
+}
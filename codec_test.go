@@ -0,0 +1,32 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JSONCodec(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	var codec Codec = JSONCodec{}
+	data, err := codec.Marshal(point{X: 1, Y: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"X":1,"Y":2}`, string(data))
+
+	var p point
+	err = codec.Unmarshal(data, &p)
+	assert.Nil(t, err)
+	assert.Equal(t, point{X: 1, Y: 2}, p)
+
+	err = codec.Unmarshal([]byte("{"), &p)
+	assert.NotNil(t, err)
+}
+
+func Test_WithCodec(t *testing.T) {
+	o := newOptions(nil)
+	assert.Equal(t, JSONCodec{}, o.codecOrDefault())
+	o = newOptions([]Option{WithCodec(JSONCodec{})})
+	assert.Equal(t, JSONCodec{}, o.codec)
+}
@@ -0,0 +1,104 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+)
+
+// Propagator carries a context across an asynchronous publication or a process boundary,
+// by writing the values of the context to the headers of a message and reading them back.
+// TraceContext is a Propagator for the W3C traceparent header.
+type Propagator interface {
+	// Inject writes the values of ctx to be propagated into headers.
+	Inject(ctx context.Context, headers map[string]string)
+	// Extract returns a copy of ctx carrying the values read from headers.
+	Extract(ctx context.Context, headers map[string]string) context.Context
+}
+
+// WithPropagator sets the propagator injecting the context passed to PublishContext()
+// into the headers of the message, and extracting it before calling context-accepting handlers.
+// This allows the context to survive the serialization of the message, for example over a network bridge.
+func WithPropagator(propagator Propagator) Option {
+	return func(o *options) {
+		o.propagator = propagator
+	}
+}
+
+// WithContextKeys restricts the values of the context passed to PublishContext()
+// that are handed to asynchronous handlers to the given keys.
+// By default, all the values of the context are kept.
+func WithContextKeys(keys ...any) Option {
+	return func(o *options) {
+		// A non-nil slice restricts the values even if no key is given.
+		o.contextKeys = append([]any{}, keys...)
+	}
+}
+
+// detachedContext carries the values of its parent, but is never canceled
+// and has no deadline, since handlers of asynchronous messages may run
+// after the publisher's context is canceled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
+// captureContext returns the context to hand to the handlers of a message published with ctx.
+// The context of an asynchronous message is detached from the cancellation of ctx,
+// and restricted to the keys set by WithContextKeys(), if any.
+func (o *options) captureContext(ctx context.Context, sync bool) context.Context {
+	if ctx == nil {
+		return nil
+	}
+	if o.contextKeys != nil {
+		captured := context.Background()
+		for _, key := range o.contextKeys {
+			if value := ctx.Value(key); value != nil {
+				captured = context.WithValue(captured, key, value)
+			}
+		}
+		return captured
+	}
+	if sync {
+		return ctx
+	}
+	return detachedContext{parent: ctx}
+}
+
+// newContextMessage returns a message published with ctx. The propagator, if any,
+// injects ctx into the headers of the message before the values are restricted by WithContextKeys().
+func (o *options) newContextMessage(ctx context.Context, payload any, sync bool) *message {
+	m := &message{payload: payload, publishCtx: ctx}
+	if ctx != nil && o.propagator != nil {
+		m.inject(ctx, o.propagator)
+	}
+	m.ctx = o.captureContext(ctx, sync)
+	return m
+}
+
+// PublishContext is like Publish, but handlers accepting a context.Context as their
+// first parameter receive the values of ctx, typically the trace and request IDs.
+// The handlers are not affected by the cancellation of ctx.
+func (e *EventBus) PublishContext(ctx context.Context, topic string, payload any) error {
+	return e.publish(e.opts.newContextMessage(ctx, payload, false), topic, false)
+}
+
+// PublishSyncContext is the synchronous version of PublishContext.
+// Handlers accepting a context.Context as their first parameter receive ctx.
+func (e *EventBus) PublishSyncContext(ctx context.Context, topic string, payload any) error {
+	return e.publish(e.opts.newContextMessage(ctx, payload, true), topic, true)
+}
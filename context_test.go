@@ -0,0 +1,119 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+func Test_detachedContext(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey("key"), "value"), time.Hour)
+	cancel()
+
+	ctx := detachedContext{parent: parent}
+	assert.Nil(t, ctx.Done())
+	assert.Nil(t, ctx.Err())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	assert.Equal(t, "value", ctx.Value(ctxKey("key")))
+}
+
+func Test_captureContext(t *testing.T) {
+	parent := context.WithValue(context.Background(), ctxKey("a"), "1")
+	parent = context.WithValue(parent, ctxKey("b"), "2")
+
+	opts := &options{}
+	assert.Nil(t, opts.captureContext(nil, false))
+	assert.Equal(t, parent, opts.captureContext(parent, true))
+	ctx := opts.captureContext(parent, false)
+	assert.Equal(t, "1", ctx.Value(ctxKey("a")))
+	assert.Equal(t, "2", ctx.Value(ctxKey("b")))
+
+	WithContextKeys(ctxKey("a"), ctxKey("c"))(opts)
+	ctx = opts.captureContext(parent, true)
+	assert.Equal(t, "1", ctx.Value(ctxKey("a")))
+	assert.Nil(t, ctx.Value(ctxKey("b")))
+
+	WithContextKeys()(opts)
+	ctx = opts.captureContext(parent, false)
+	assert.Nil(t, ctx.Value(ctxKey("a")))
+}
+
+func Test_EventBusPublishContext(t *testing.T) {
+	bus := NewBuffered(10)
+	received := make(chan string, 2)
+	err := bus.Subscribe("testtopic", func(ctx context.Context, topic string, val int) {
+		assert.Nil(t, ctx.Err())
+		received <- ctx.Value(ctxKey("request")).(string)
+	})
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", func(ctx context.Context, env *Envelope[int]) {
+		received <- ctx.Value(ctxKey("request")).(string)
+	})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey("request"), "req-1"))
+	err = bus.PublishContext(ctx, "testtopic", 1)
+	assert.Nil(t, err)
+	// The handlers must not be affected by the cancellation of the publisher's context.
+	cancel()
+	assert.Equal(t, "req-1", <-received)
+	assert.Equal(t, "req-1", <-received)
+
+	err = bus.PublishSyncContext(context.WithValue(context.Background(), ctxKey("request"), "req-2"), "testtopic", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "req-2", <-received)
+	assert.Equal(t, "req-2", <-received)
+	bus.Close()
+}
+
+func Test_EventBusPublishWithoutContext(t *testing.T) {
+	bus := New()
+	var got context.Context
+	err := bus.Subscribe("testtopic", func(ctx context.Context, topic string, val int) {
+		got = ctx
+	})
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, context.Background(), got)
+	bus.Close()
+}
+
+func Test_EventBusPropagator(t *testing.T) {
+	bus := NewWithOptions(WithPropagator(TraceContext{}), WithContextKeys())
+	sc := SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Flags: 1}
+
+	var headers map[string]string
+	var extracted SpanContext
+	err := bus.Subscribe("testtopic", func(ctx context.Context, env *Envelope[int]) {
+		headers = env.Headers
+		extracted, _ = SpanContextFromContext(ctx)
+	})
+	assert.Nil(t, err)
+
+	published := map[string]string{"key": "value"}
+	err = bus.PublishEnvelopeSync(&Envelope[int]{Topic: "testtopic", Headers: published})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"key": "value"}, headers)
+
+	// WithContextKeys() without keys drops every value, so the span context
+	// only reaches the handler through the traceparent header.
+	err = bus.PublishSyncContext(ContextWithSpanContext(context.Background(), sc), "testtopic", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, sc.Traceparent(), headers[TraceparentHeader])
+	assert.Equal(t, sc, extracted)
+
+	err = bus.PublishEnvelopeSync(&Envelope[int]{
+		Topic:   "testtopic",
+		Headers: map[string]string{TraceparentHeader: sc.Traceparent()},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, sc, extracted)
+	assert.Equal(t, map[string]string{"key": "value"}, published)
+	bus.Close()
+}
@@ -0,0 +1,176 @@
+package eventbus
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds the search of the next time matching a cron expression,
+// so that an expression which never matches, like "0 0 0 30 2 *", does not loop forever.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronField describes a field of a cron expression.
+type cronField struct {
+	min, max int
+	// names maps the names accepted by the field to their values, like "JAN" for the months.
+	names map[string]int
+}
+
+var (
+	cronSeconds = cronField{min: 0, max: 59}
+	cronMinutes = cronField{min: 0, max: 59}
+	cronHours   = cronField{min: 0, max: 23}
+	cronDays    = cronField{min: 1, max: 31}
+	cronMonths  = cronField{min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// The day 7 is accepted as Sunday, and folded into 0 once parsed.
+	cronWeekdays = cronField{min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+// cronSchedule is a parsed cron expression. Each field is a bit set of the values it matches.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// domAny and dowAny are true if the day of the month or the day of the week is "*" or "?".
+	domAny, dowAny bool
+}
+
+// parseCron parses a cron expression made of 6 fields separated by spaces:
+// second, minute, hour, day of the month, month and day of the week.
+// Each field is "*", a value, a range "a-b", or a list of them separated by commas,
+// each optionally followed by a step "/n". The months and the days of the week accept
+// their first three letters in English, like "JAN" or "MON". "?" is a synonym of "*"
+// for the days. As in the standard cron, a day matches if it matches either the day
+// of the month or the day of the week, unless one of them is "*".
+// Returns ErrInvalidSchedule if the expression is malformed.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 6 {
+		return nil, ErrInvalidSchedule
+	}
+
+	s := &cronSchedule{
+		domAny: fields[3] == "*" || fields[3] == "?",
+		dowAny: fields[5] == "*" || fields[5] == "?",
+	}
+	specs := []struct {
+		bits  *uint64
+		field cronField
+	}{
+		{&s.second, cronSeconds},
+		{&s.minute, cronMinutes},
+		{&s.hour, cronHours},
+		{&s.dom, cronDays},
+		{&s.month, cronMonths},
+		{&s.dow, cronWeekdays},
+	}
+	for i, spec := range specs {
+		bits, err := spec.field.parse(fields[i])
+		if err != nil {
+			return nil, err
+		}
+		*spec.bits = bits
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+// parse parses a field of a cron expression into a bit set.
+func (f cronField) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepExpr); err != nil || step <= 0 {
+				return 0, ErrInvalidSchedule
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			loExpr, hiExpr, _ := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(loExpr); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiExpr); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if lo, err = f.value(rangeExpr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if hasStep {
+				// "a/n" means every n from a.
+				hi = f.max
+			}
+		}
+		if lo > hi {
+			return 0, ErrInvalidSchedule
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value of the field, given as a number or a name.
+func (f cronField) value(expr string) (int, error) {
+	if v, ok := f.names[strings.ToUpper(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < f.min || v > f.max {
+		return 0, ErrInvalidSchedule
+	}
+	return v, nil
+}
+
+// next returns the first time strictly after t matching the schedule, in the location of t.
+// It returns the zero time if no time matches within the next five years.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(cronSearchLimit)
+	t = t.Truncate(time.Second).Add(time.Second)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case s.second&(1<<uint(t.Second())) == 0:
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether the day of t matches the day of the month and the day of the week of the schedule.
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,78 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseCron(t *testing.T) {
+	s, err := parseCron("0 */5 * * * *")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), s.second)
+	assert.Equal(t, uint64(1|1<<5|1<<10|1<<15|1<<20|1<<25|1<<30|1<<35|1<<40|1<<45|1<<50|1<<55), s.minute)
+	assert.True(t, s.domAny)
+	assert.True(t, s.dowAny)
+
+	s, err = parseCron("30 0 9-17/4 ? jan,Mar-APR MON-FRI,7")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1<<30), s.second)
+	assert.Equal(t, uint64(1<<9|1<<13|1<<17), s.hour)
+	assert.Equal(t, uint64(1<<1|1<<3|1<<4), s.month)
+	assert.Equal(t, uint64(1|1<<1|1<<2|1<<3|1<<4|1<<5), s.dow)
+	assert.True(t, s.domAny)
+	assert.False(t, s.dowAny)
+
+	s, err = parseCron("5/20 0 0 1 1 *")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1<<5|1<<25|1<<45), s.second)
+
+	for _, expr := range []string{
+		"",
+		"* * * * *",
+		"* * * * * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"*/0 * * * * *",
+		"5-1 * * * * *",
+		"a * * * * *",
+		"1-x * * * * *",
+		"* * * * FOO *",
+	} {
+		_, err := parseCron(expr)
+		assert.Equal(t, ErrInvalidSchedule, err, expr)
+	}
+}
+
+func Test_CronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation(time.DateTime, s, time.UTC)
+		assert.Nil(t, err)
+		return tm
+	}
+	next := func(expr string, from string) time.Time {
+		s, err := parseCron(expr)
+		assert.Nil(t, err)
+		return s.next(at(from))
+	}
+
+	assert.Equal(t, at("2024-01-01 00:00:01"), next("* * * * * *", "2024-01-01 00:00:00"))
+	assert.Equal(t, at("2024-01-01 00:05:00"), next("0 */5 * * * *", "2024-01-01 00:00:00"))
+	assert.Equal(t, at("2024-01-01 00:05:00"), next("0 */5 * * * *", "2024-01-01 00:04:59"))
+	assert.Equal(t, at("2024-01-01 01:00:00"), next("0 0 * * * *", "2024-01-01 00:00:00"))
+	assert.Equal(t, at("2024-01-02 09:30:00"), next("0 30 9 * * *", "2024-01-01 09:30:00"))
+	assert.Equal(t, at("2024-02-29 00:00:00"), next("0 0 0 29 2 *", "2023-03-01 00:00:00"))
+	assert.Equal(t, at("2025-01-01 00:00:00"), next("0 0 0 1 1 *", "2024-06-15 12:00:00"))
+	// 2024-01-01 is a Monday.
+	assert.Equal(t, at("2024-01-06 08:00:00"), next("0 0 8 * * SAT", "2024-01-01 00:00:00"))
+	assert.Equal(t, at("2024-01-07 00:00:00"), next("0 0 0 * * 7", "2024-01-01 00:00:00"))
+	// Either the day of the month or the day of the week matches.
+	assert.Equal(t, at("2024-01-05 00:00:00"), next("0 0 0 15 * FRI", "2024-01-01 00:00:00"))
+	assert.Equal(t, at("2024-01-15 00:00:00"), next("0 0 0 15 * FRI", "2024-01-12 00:00:00"))
+	// February never has 30 days.
+	assert.True(t, next("0 0 0 30 2 *", "2024-01-01 00:00:00").IsZero())
+}
@@ -0,0 +1,109 @@
+package eventbus
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultDedupSize is the default number of IDs remembered by a deduplicated topic.
+const defaultDedupSize = 10000
+
+// Dedup makes a topic drop the messages whose ID has already been published to the topic recently,
+// like the messages published again by a publisher retrying. The ID of a message is the ID of its
+// envelope, see PublishEnvelope(), or the key returned by the function set with DedupBy().
+// The topic remembers the last size IDs (10000 if size is not positive), and forgets the IDs
+// not seen for maxAge. Zero maxAge means no limit of age. The duplicates are reported to the
+// observer with ErrDuplicate, and Publish() returns nil for them.
+func Dedup(size int, maxAge time.Duration) TopicOption {
+	return func(o *topicOptions) {
+		o.dedup = true
+		o.dedupSize = size
+		o.dedupAge = maxAge
+	}
+}
+
+// DedupBy makes a deduplicated topic identify its messages by the key returned by fn for their payload,
+// instead of their ID. The messages for which fn returns an empty key are never considered duplicates.
+// It enables the deduplication with the default limits if Dedup() is not set.
+func DedupBy(fn func(payload any) string) TopicOption {
+	return func(o *topicOptions) {
+		o.dedup = true
+		o.dedupKey = fn
+	}
+}
+
+// dedupEntry is an ID remembered by a deduplicated topic.
+type dedupEntry struct {
+	key  string
+	seen time.Time
+}
+
+// dedup remembers the IDs recently published to a topic, in a bounded LRU list.
+type dedup struct {
+	mu     sync.Mutex
+	size   int
+	maxAge time.Duration
+	// keys indexes the elements of order by ID.
+	keys map[string]*list.Element
+	// order holds the IDs from the most recently seen to the least recently seen.
+	order *list.List
+}
+
+// newDedup creates a dedup remembering at most size IDs for at most maxAge.
+func newDedup(size int, maxAge time.Duration) *dedup {
+	if size <= 0 {
+		size = defaultDedupSize
+	}
+	return &dedup{
+		size:   size,
+		maxAge: maxAge,
+		keys:   make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// seen records that an ID has been published at now, and reports whether it had already been seen.
+func (d *dedup) seen(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(now)
+	if e, ok := d.keys[key]; ok {
+		e.Value.(*dedupEntry).seen = now
+		d.order.MoveToFront(e)
+		return true
+	}
+	d.keys[key] = d.order.PushFront(&dedupEntry{key: key, seen: now})
+	if d.order.Len() > d.size {
+		d.remove(d.order.Back())
+	}
+	return false
+}
+
+// expire forgets the IDs not seen for maxAge.
+func (d *dedup) expire(now time.Time) {
+	if d.maxAge <= 0 {
+		return
+	}
+	for e := d.order.Back(); e != nil && now.Sub(e.Value.(*dedupEntry).seen) > d.maxAge; e = d.order.Back() {
+		d.remove(e)
+	}
+}
+
+// remove forgets an ID.
+func (d *dedup) remove(e *list.Element) {
+	d.order.Remove(e)
+	delete(d.keys, e.Value.(*dedupEntry).key)
+}
+
+// duplicate reports whether a message published to the channel is a duplicate.
+func (c *channel) duplicate(m *message, now time.Time) bool {
+	if c.dedup == nil {
+		return false
+	}
+	key := m.id
+	if c.config.dedupKey != nil {
+		key = c.config.dedupKey(m.payload)
+	}
+	return key != "" && c.dedup.seen(key, now)
+}
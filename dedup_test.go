@@ -0,0 +1,86 @@
+package eventbus
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DedupSeen(t *testing.T) {
+	now := time.Now()
+	d := newDedup(2, time.Minute)
+	assert.False(t, d.seen("a", now))
+	assert.True(t, d.seen("a", now))
+	assert.False(t, d.seen("b", now))
+	assert.False(t, d.seen("c", now))
+	// "a" is the least recently seen ID, so it has been forgotten.
+	assert.False(t, d.seen("a", now))
+	assert.Equal(t, 2, d.order.Len())
+	assert.Equal(t, 2, len(d.keys))
+
+	// "c" has been seen again after "a", so it is still remembered.
+	assert.True(t, d.seen("c", now.Add(30*time.Second)))
+	assert.False(t, d.seen("a", now.Add(90*time.Second)))
+	assert.True(t, d.seen("c", now.Add(90*time.Second)))
+
+	d = newDedup(0, 0)
+	assert.Equal(t, defaultDedupSize, d.size)
+	assert.False(t, d.seen("a", now))
+	assert.True(t, d.seen("a", now.Add(time.Hour)))
+}
+
+func Test_DedupEnvelopeID(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer), WithTopic("testtopic", Dedup(100, time.Minute)))
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = bus.PublishEnvelopeSync(&Envelope[int]{Topic: "testtopic", ID: "order-1", Payload: 1})
+		assert.Nil(t, err)
+	}
+	err = bus.PublishEnvelopeSync(&Envelope[int]{Topic: "testtopic", ID: "order-2", Payload: 2})
+	assert.Nil(t, err)
+	// The IDs generated by Publish are unique.
+	assert.Nil(t, bus.PublishSync("testtopic", 3))
+	assert.Nil(t, bus.PublishSync("testtopic", 3))
+	assert.Equal(t, int32(4), received.Load())
+	assert.Equal(t, uint64(2), bus.Stats()["testtopic"].Dropped)
+	bus.Close()
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrDuplicate.Error())
+}
+
+func Test_DedupBy(t *testing.T) {
+	bus := NewWithOptions(WithTopic("testtopic", DedupBy(func(payload any) string {
+		if payload.(int) < 0 {
+			return ""
+		}
+		return strconv.Itoa(payload.(int))
+	})))
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	for _, val := range []int{1, 2, 1, 2, 3, -1, -1} {
+		assert.Nil(t, bus.PublishSync("testtopic", val))
+	}
+	assert.Equal(t, int32(5), received.Load())
+
+	// Other topics are not deduplicated.
+	err = bus.Subscribe("other", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, bus.PublishSync("other", 1))
+	assert.Nil(t, bus.PublishSync("other", 1))
+	assert.Equal(t, int32(7), received.Load())
+	bus.Close()
+}
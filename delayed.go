@@ -0,0 +1,295 @@
+package eventbus
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// WithFlushDelayedOnClose makes Close() publish synchronously the messages scheduled with
+// PublishAfter() or PublishAt() which are not due yet, instead of dropping them.
+func WithFlushDelayedOnClose() Option {
+	return func(o *options) {
+		o.flushDelayed = true
+	}
+}
+
+// DelayedMessage is a message scheduled with PublishAfter() or PublishAt().
+type DelayedMessage struct {
+	topic   string
+	payload any
+	at      time.Time
+	// index is the position of the message in the queue of the scheduler, -1 once it has left the queue.
+	index int
+	s     *scheduler
+}
+
+// Topic returns the topic the message will be published to.
+func (m *DelayedMessage) Topic() string {
+	return m.topic
+}
+
+// Time returns the time the message is scheduled for.
+func (m *DelayedMessage) Time() time.Time {
+	return m.at
+}
+
+// Cancel cancels the publication of the message.
+// It reports false if the message has already been published, dropped or canceled.
+func (m *DelayedMessage) Cancel() bool {
+	return m.s.cancel(m)
+}
+
+// PublishAfter publishes a payload to a topic asynchronously once d has elapsed, like Publish().
+// The returned message may be canceled until it is published. The messages not published yet
+// when the eventbus is closed are dropped, unless WithFlushDelayedOnClose() is set.
+// All the delayed messages of an eventbus are scheduled by a single goroutine, and published by
+// a goroutine per topic, so that the slow handlers of a topic only delay the messages of their topic.
+// The observer is notified with OnDrop() of the messages which could not be published.
+func (e *EventBus) PublishAfter(topic string, payload any, d time.Duration) (*DelayedMessage, error) {
+	return e.PublishAt(topic, payload, time.Now().Add(d))
+}
+
+// PublishAt publishes a payload to a topic asynchronously at the given time, like PublishAfter().
+// A time in the past publishes the payload right away.
+func (e *EventBus) PublishAt(topic string, payload any, t time.Time) (*DelayedMessage, error) {
+	m := &DelayedMessage{topic: topic, payload: payload, at: t, s: e.scheduler}
+	if err := e.scheduler.schedule(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// closeDelayed stops the scheduler of the eventbus, then publishes synchronously or drops
+// the delayed messages not due yet. It waits for the due messages to be published, at most
+// until the deadline if it is not zero, and returns the topics whose messages are still being published.
+func (e *EventBus) closeDelayed(deadline time.Time) []string {
+	pending := e.scheduler.close()
+	if e.opts.flushDelayed {
+		e.scheduler.handOff(pending)
+	} else if e.opts.observer != nil {
+		for _, m := range pending {
+			e.opts.observer.OnDrop(m.topic, m.payload, ErrChannelClosed)
+		}
+	}
+	return e.scheduler.wait(deadline)
+}
+
+// delayedQueue is a min-heap of delayed messages ordered by time, implementing heap.Interface.
+type delayedQueue []*DelayedMessage
+
+func (q delayedQueue) Len() int {
+	return len(q)
+}
+
+func (q delayedQueue) Less(i, j int) bool {
+	return q[i].at.Before(q[j].at)
+}
+
+func (q delayedQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *delayedQueue) Push(x any) {
+	m := x.(*DelayedMessage)
+	m.index = len(*q)
+	*q = append(*q, m)
+}
+
+func (q *delayedQueue) Pop() any {
+	old := *q
+	n := len(old)
+	m := old[n-1]
+	old[n-1] = nil
+	m.index = -1
+	*q = old[:n-1]
+	return m
+}
+
+// scheduler hands the delayed messages of an eventbus over to the goroutine of their topic when they
+// are due, from a single goroutine started with the first delayed message.
+type scheduler struct {
+	mu      sync.Mutex
+	queue   delayedQueue
+	closed  bool
+	started bool
+	// wake wakes up the goroutine when the earliest message changes.
+	wake   chan struct{}
+	stopCh chan struct{}
+	doneCh chan struct{}
+	// outbox holds the due messages of the topics being published, in chronological order.
+	outbox map[string][]*DelayedMessage
+	// publishing counts the goroutines publishing the due messages.
+	publishing sync.WaitGroup
+	// publish publishes a due message, synchronously once the scheduler is closed.
+	publish func(topic string, payload any, sync bool)
+}
+
+// newScheduler creates a scheduler publishing the due messages with publish.
+func newScheduler(publish func(topic string, payload any, sync bool)) *scheduler {
+	return &scheduler{
+		wake:    make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+		outbox:  make(map[string][]*DelayedMessage),
+		publish: publish,
+	}
+}
+
+// schedule adds a message to the queue.
+// Returns ErrChannelClosed if the scheduler has been closed.
+func (s *scheduler) schedule(m *DelayedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrChannelClosed
+	}
+	heap.Push(&s.queue, m)
+	if !s.started {
+		s.started = true
+		go s.run()
+	}
+	if m.index == 0 {
+		notify(s.wake)
+	}
+	return nil
+}
+
+// cancel removes a message from the queue, and reports whether it was still there.
+func (s *scheduler) cancel(m *DelayedMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.index < 0 {
+		return false
+	}
+	heap.Remove(&s.queue, m.index)
+	return true
+}
+
+// due removes the messages due at now from the queue.
+// It also returns the time until the next message, or a negative duration if the queue is empty.
+func (s *scheduler) due(now time.Time) ([]*DelayedMessage, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*DelayedMessage
+	for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+		due = append(due, heap.Pop(&s.queue).(*DelayedMessage))
+	}
+	if len(s.queue) == 0 {
+		return due, -1
+	}
+	return due, s.queue[0].at.Sub(now)
+}
+
+// run publishes the messages when they are due, until the scheduler is closed.
+func (s *scheduler) run() {
+	defer close(s.doneCh)
+	for {
+		due, next := s.due(time.Now())
+		s.handOff(due)
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if next >= 0 {
+			timer = time.NewTimer(next)
+			timeout = timer.C
+		}
+		select {
+		case <-timeout:
+		case <-s.wake:
+		case <-s.stopCh:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if s.stopped() {
+			return
+		}
+	}
+}
+
+// handOff queues the due messages in the outbox of their topic, and starts the goroutine
+// publishing the messages of the topic if it is not running.
+func (s *scheduler) handOff(due []*DelayedMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range due {
+		queued, running := s.outbox[m.topic]
+		s.outbox[m.topic] = append(queued, m)
+		if !running {
+			s.publishing.Add(1)
+			go s.publishTopic(m.topic)
+		}
+	}
+}
+
+// publishTopic publishes the messages of the outbox of a topic, until it is empty.
+func (s *scheduler) publishTopic(topic string) {
+	defer s.publishing.Done()
+	for {
+		s.mu.Lock()
+		queued := s.outbox[topic]
+		if len(queued) == 0 {
+			delete(s.outbox, topic)
+			s.mu.Unlock()
+			return
+		}
+		m := queued[0]
+		s.outbox[topic] = queued[1:]
+		sync := s.closed
+		s.mu.Unlock()
+		s.publish(m.topic, m.payload, sync)
+	}
+}
+
+// wait waits for the due messages to be published, at most until the deadline if it is not zero.
+// It returns the topics whose messages are still being published.
+func (s *scheduler) wait(deadline time.Time) []string {
+	if waitUntil(&s.publishing, deadline) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	topics := make([]string, 0, len(s.outbox))
+	for topic := range s.outbox {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// stopped reports whether the scheduler has been closed.
+func (s *scheduler) stopped() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the scheduler and waits for its goroutine to exit, which never blocks on the publication
+// of the messages. It returns the messages which are not due yet, in chronological order.
+func (s *scheduler) close() []*DelayedMessage {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	started := s.started
+	close(s.stopCh)
+	s.mu.Unlock()
+	if started {
+		<-s.doneCh
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make([]*DelayedMessage, 0, len(s.queue))
+	for len(s.queue) > 0 {
+		pending = append(pending, heap.Pop(&s.queue).(*DelayedMessage))
+	}
+	return pending
+}
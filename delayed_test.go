@@ -0,0 +1,172 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// delayedRecorder records the payloads received by a handler.
+type delayedRecorder struct {
+	sync.Mutex
+	received []int
+}
+
+func (r *delayedRecorder) handle(topic string, val int) {
+	r.Lock()
+	defer r.Unlock()
+	r.received = append(r.received, val)
+}
+
+func (r *delayedRecorder) values() []int {
+	r.Lock()
+	defer r.Unlock()
+	return append([]int(nil), r.received...)
+}
+
+func Test_PublishAfter(t *testing.T) {
+	bus := New()
+	recorder := &delayedRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	start := time.Now()
+	m, err := bus.PublishAfter("testtopic", 3, 30*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, "testtopic", m.Topic())
+	_, err = bus.PublishAfter("testtopic", 2, 20*time.Millisecond)
+	assert.Nil(t, err)
+	_, err = bus.PublishAt("testtopic", 1, start.Add(10*time.Millisecond))
+	assert.Nil(t, err)
+	_, err = bus.PublishAt("testtopic", 0, start.Add(-time.Second))
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool { return len(recorder.values()) == 4 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.Equal(t, []int{0, 1, 2, 3}, recorder.values())
+	assert.False(t, m.Cancel())
+	bus.Close()
+}
+
+func Test_PublishAfterCancel(t *testing.T) {
+	bus := New()
+	recorder := &delayedRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	m1, err := bus.PublishAfter("testtopic", 1, 20*time.Millisecond)
+	assert.Nil(t, err)
+	m2, err := bus.PublishAfter("testtopic", 2, 20*time.Millisecond)
+	assert.Nil(t, err)
+	_, err = bus.PublishAfter("testtopic", 3, 40*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, m1.Cancel())
+	assert.False(t, m1.Cancel())
+
+	assert.Eventually(t, func() bool { return len(recorder.values()) == 2 }, time.Second, time.Millisecond)
+	assert.False(t, m2.Cancel())
+	assert.Equal(t, []int{2, 3}, recorder.values())
+	bus.Close()
+}
+
+func Test_PublishAfterClose(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	recorder := &delayedRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	m, err := bus.PublishAfter("testtopic", 1, time.Hour)
+	assert.Nil(t, err)
+	bus.Close()
+	assert.False(t, m.Cancel())
+	assert.Empty(t, recorder.values())
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrChannelClosed.Error())
+
+	_, err = bus.PublishAfter("testtopic", 1, time.Millisecond)
+	assert.Equal(t, ErrChannelClosed, err)
+}
+
+func Test_PublishAfterFlush(t *testing.T) {
+	bus := NewWithOptions(WithFlushDelayedOnClose())
+	recorder := &delayedRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	_, err = bus.PublishAfter("testtopic", 2, 2*time.Hour)
+	assert.Nil(t, err)
+	_, err = bus.PublishAfter("testtopic", 1, time.Hour)
+	assert.Nil(t, err)
+	bus.Close()
+	assert.Equal(t, []int{1, 2}, recorder.values())
+}
+
+func Test_PublishAfterSlowTopic(t *testing.T) {
+	bus := New()
+	release := make(chan struct{})
+	err := bus.Subscribe("slow", func(topic string, val int) { <-release })
+	assert.Nil(t, err)
+	recorder := &delayedRecorder{}
+	err = bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	// The second message of the slow topic waits for its handler, but not the messages of the other topics.
+	_, err = bus.PublishAfter("slow", 1, 0)
+	assert.Nil(t, err)
+	_, err = bus.PublishAfter("slow", 2, time.Millisecond)
+	assert.Nil(t, err)
+	_, err = bus.PublishAfter("testtopic", 3, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(recorder.values()) == 1 }, time.Second, time.Millisecond)
+
+	// The close timeout covers the delayed messages being published.
+	start := time.Now()
+	err = bus.CloseTimeout(50 * time.Millisecond)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	var timeoutErr *CloseTimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, []string{"slow"}, timeoutErr.Topics)
+	close(release)
+}
+
+func Test_PublishAfterError(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer), WithBufferSize(1), WithBackpressure(BackpressureError),
+		WithDataDir(t.TempDir()), WithTopic("orders", Durable[durableEvent]()))
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		started <- struct{}{}
+		<-release
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, bus.Publish("testtopic", 1))
+	<-started
+	assert.Nil(t, bus.Publish("testtopic", 2))
+
+	// The messages which can't be published are reported once to the observer.
+	_, err = bus.PublishAfter("testtopic", 3, 0)
+	assert.Nil(t, err)
+	_, err = bus.PublishAfter("orders", 4, 0)
+	assert.Nil(t, err)
+	count := func(event string) int {
+		n := 0
+		for _, e := range observer.Events() {
+			if e == event {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Eventually(t, func() bool {
+		return count("drop:orders:"+ErrPayloadType.Error()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return count("drop:testtopic:"+ErrQueueFull.Error()) == 1
+	}, time.Second, time.Millisecond)
+	close(release)
+	bus.Close()
+	assert.Equal(t, 1, count("drop:testtopic:"+ErrQueueFull.Error()))
+}
@@ -0,0 +1,173 @@
+package eventbus
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// defaultVisibilityTimeout is the default time a handler has to settle a delivery before it is redelivered.
+const defaultVisibilityTimeout = 30 * time.Second
+
+// deliveryType is the type of the interface implemented by every *Delivery[T].
+var deliveryType = reflect.TypeOf((*anyDelivery)(nil)).Elem()
+
+// WithVisibilityTimeout sets the time a handler receiving a *Delivery[T] has to acknowledge it.
+// A delivery which is neither acknowledged, rejected nor extended in time is redelivered to the same handler.
+// The default timeout is 30 seconds.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.visibilityTimeout = timeout
+	}
+}
+
+// Delivery is a message to acknowledge explicitly, received by the handlers of the form func(d *Delivery[T]).
+// The handler may return before settling the delivery, for instance to hand the message to a pool
+// of goroutines, which acknowledges it later with Ack(). A delivery which is not settled within the
+// visibility timeout set with WithVisibilityTimeout() is redelivered to the same handler, as a new Delivery,
+// or to a member of the same group for the handlers subscribed with SubscribeGroup().
+// Redeliveries happen from their own goroutines, concurrently with the other messages of the topic.
+type Delivery[T any] struct {
+	Envelope[T]
+	// Attempt is the number of times the message has been delivered to the handler, starting at 1.
+	Attempt int
+	ack     *ack
+}
+
+// anyDelivery is implemented by *Delivery[T] for every T.
+type anyDelivery interface {
+	fromAck(a *ack)
+}
+
+// fromAck fills the delivery with the message of an acknowledgement.
+// It panics if the payload of the message is not of type T.
+func (d *Delivery[T]) fromAck(a *ack) {
+	d.fromMessage(a.m)
+	d.Attempt = a.attempt
+	d.ack = a
+}
+
+// Ack acknowledges the delivery: the message is never delivered again to the handler.
+// Returns ErrDeliverySettled if the delivery has already been settled or has timed out.
+func (d *Delivery[T]) Ack() error {
+	return d.ack.settle()
+}
+
+// Nack rejects the delivery. If requeue is true, the message is redelivered to the handler right away,
+// otherwise it is dropped and reported to the observer with ErrNacked.
+// Returns ErrDeliverySettled if the delivery has already been settled or has timed out.
+func (d *Delivery[T]) Nack(requeue bool) error {
+	if err := d.ack.settle(); err != nil {
+		return err
+	}
+	if requeue {
+		d.ack.redeliver()
+	} else {
+		d.ack.c.drop(d.ack.m.payload, ErrNacked)
+	}
+	return nil
+}
+
+// Extend restarts the visibility timeout of the delivery, giving the handler more time to settle it.
+// Returns ErrDeliverySettled if the delivery has already been settled or has timed out.
+func (d *Delivery[T]) Extend() error {
+	return d.ack.extend()
+}
+
+// ack tracks a delivery until it is settled or its visibility timeout expires.
+type ack struct {
+	c       *channel
+	h       *handler
+	m       *message
+	ctx     context.Context
+	attempt int
+	timeout time.Duration
+
+	mu      sync.Mutex
+	settled bool
+	timer   *time.Timer
+}
+
+// newAck starts the visibility timeout of a delivery of a message to a handler.
+func (c *channel) newAck(ctx context.Context, h *handler, m *message, attempt int) *ack {
+	a := &ack{c: c, h: h, m: m, ctx: ctx, attempt: attempt, timeout: c.visibilityTimeout()}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.timer = time.AfterFunc(a.timeout, a.expire)
+	return a
+}
+
+// visibilityTimeout returns the time a handler has to settle a delivery.
+func (c *channel) visibilityTimeout() time.Duration {
+	if c.opts.visibilityTimeout > 0 {
+		return c.opts.visibilityTimeout
+	}
+	return defaultVisibilityTimeout
+}
+
+// settle marks the delivery as settled and stops its visibility timeout.
+func (a *ack) settle() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.settled {
+		return ErrDeliverySettled
+	}
+	a.settled = true
+	a.timer.Stop()
+	return nil
+}
+
+// extend restarts the visibility timeout of the delivery.
+func (a *ack) extend() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// If the timer has already fired, the delivery is being redelivered.
+	if a.settled || !a.timer.Stop() {
+		return ErrDeliverySettled
+	}
+	a.timer.Reset(a.timeout)
+	return nil
+}
+
+// expire redelivers the message when the visibility timeout expires.
+func (a *ack) expire() {
+	a.mu.Lock()
+	if a.settled {
+		a.mu.Unlock()
+		return
+	}
+	a.settled = true
+	a.mu.Unlock()
+	a.redeliver()
+}
+
+// redeliver delivers the message again to the handler from a new goroutine,
+// unless the channel has been stopped or the handler has been unsubscribed.
+func (a *ack) redeliver() {
+	c := a.c
+	if !c.enter() {
+		c.drop(a.m.payload, ErrChannelClosed)
+		return
+	}
+	go func() {
+		defer c.exit()
+		if c.stopped() {
+			c.drop(a.m.payload, ErrChannelClosed)
+			return
+		}
+		if a.h.group != "" {
+			g, ok := c.groups.Load(a.h.group)
+			if !ok || !c.callGroup(a.ctx, g.(*group), a.m, a.attempt+1, false) {
+				c.drop(a.m.payload, ErrNoSubscriber)
+			}
+			return
+		}
+		h, ok := c.handlers.Load(a.h.fn.Pointer())
+		if !ok {
+			c.drop(a.m.payload, ErrNoSubscriber)
+			return
+		}
+		c.callAttempt(a.ctx, h.(*handler), a.m, a.attempt+1)
+	}()
+}
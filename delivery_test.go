@@ -0,0 +1,161 @@
+package eventbus
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deliveryRecorder records the deliveries received by a handler.
+type deliveryRecorder struct {
+	sync.Mutex
+	deliveries []*Delivery[int]
+}
+
+func (r *deliveryRecorder) handle(d *Delivery[int]) {
+	r.Lock()
+	defer r.Unlock()
+	r.deliveries = append(r.deliveries, d)
+}
+
+func (r *deliveryRecorder) len() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.deliveries)
+}
+
+func (r *deliveryRecorder) get(i int) *Delivery[int] {
+	r.Lock()
+	defer r.Unlock()
+	return r.deliveries[i]
+}
+
+func Test_DeliveryHandler(t *testing.T) {
+	h, err := newHandler(reflect.ValueOf(func(d *Delivery[int]) {}))
+	assert.Nil(t, err)
+	assert.True(t, h.delivery)
+	assert.False(t, h.envelope)
+}
+
+func Test_DeliveryAck(t *testing.T) {
+	bus := NewWithOptions(WithVisibilityTimeout(20 * time.Millisecond))
+	recorder := &deliveryRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, recorder.len())
+	d := recorder.get(0)
+	assert.Equal(t, 1, d.Payload)
+	assert.Equal(t, 1, d.Attempt)
+	assert.Equal(t, "testtopic", d.Topic)
+	assert.Nil(t, d.Ack())
+	assert.Equal(t, ErrDeliverySettled, d.Ack())
+	assert.Equal(t, ErrDeliverySettled, d.Nack(true))
+	assert.Equal(t, ErrDeliverySettled, d.Extend())
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, recorder.len())
+	bus.Close()
+}
+
+func Test_DeliveryTimeout(t *testing.T) {
+	bus := NewWithOptions(WithVisibilityTimeout(10 * time.Millisecond))
+	recorder := &deliveryRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	err = bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return recorder.len() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, ErrDeliverySettled, recorder.get(0).Ack())
+	d := recorder.get(1)
+	assert.Equal(t, 1, d.Payload)
+	assert.Equal(t, 2, d.Attempt)
+	assert.Equal(t, recorder.get(0).ID, d.ID)
+	assert.Nil(t, d.Ack())
+	bus.Close()
+}
+
+func Test_DeliveryExtend(t *testing.T) {
+	bus := NewWithOptions(WithVisibilityTimeout(30 * time.Millisecond))
+	recorder := &deliveryRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	d := recorder.get(0)
+	for i := 0; i < 4; i++ {
+		time.Sleep(15 * time.Millisecond)
+		assert.Nil(t, d.Extend())
+	}
+	assert.Nil(t, d.Ack())
+	assert.Equal(t, 1, recorder.len())
+	bus.Close()
+}
+
+func Test_DeliveryNack(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	recorder := &deliveryRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.get(0).Nack(true))
+	assert.Eventually(t, func() bool { return recorder.len() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, recorder.get(1).Attempt)
+	assert.Nil(t, recorder.get(1).Nack(false))
+	assert.Equal(t, ErrDeliverySettled, recorder.get(1).Nack(false))
+	bus.Close()
+
+	assert.Equal(t, 2, recorder.len())
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrNacked.Error())
+}
+
+func Test_DeliveryUnsubscribed(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	recorder := &deliveryRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	err = bus.Unsubscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.get(0).Nack(true))
+	assert.Eventually(t, func() bool {
+		for _, event := range observer.Events() {
+			if event == "drop:testtopic:"+ErrNoSubscriber.Error() {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, recorder.len())
+	bus.Close()
+}
+
+func Test_DeliveryClose(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer), WithVisibilityTimeout(time.Hour))
+	recorder := &deliveryRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle)
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	bus.Close()
+	assert.Nil(t, recorder.get(0).Nack(true))
+	assert.Equal(t, 1, recorder.len())
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrChannelClosed.Error())
+}
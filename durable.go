@@ -0,0 +1,350 @@
+package eventbus
+
+import (
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultSegmentSize is the default size above which a new segment of a write-ahead log is started.
+	defaultSegmentSize = 16 << 20
+	// dispatchedOffsetFile is the name of the file storing the sequence number
+	// of the last message dispatched to the handlers of a durable topic.
+	dispatchedOffsetFile = "dispatched.offset"
+	// topicDirExt is the extension of the directories of the durable topics.
+	topicDirExt = ".topic"
+)
+
+// durableConfig holds the settings of a durable topic depending on the type of its payloads.
+type durableConfig struct {
+	// check reports whether a payload can be published to the topic.
+	check func(payload any) bool
+	// decode decodes a payload stored in the write-ahead log.
+	decode func(codec Codec, data []byte) (any, error)
+}
+
+// Durable makes a topic persistent: its messages are appended to a write-ahead log
+// in the directory set with WithDataDir() before being dispatched, and survive a crash or a restart.
+// T is the type of the payloads of the topic, which are encoded with the Codec set with WithCodec().
+// Publishing a payload of another type returns ErrPayloadType.
+//
+// A durable topic keeps its messages until it has handlers, instead of dropping them:
+// the messages not dispatched before a restart are delivered once a handler subscribes.
+// The write-ahead log also serves as the history of the topic for SubscribeFrom().
+// The messages are dispatched by a single goroutine, whatever WithWorkers(). Only the headers of
+// the messages are stored, so the contexts given to PublishContext() are propagated with a Propagator only.
+// A durable topic is stateful, see TopicOption.
+func Durable[T any]() TopicOption {
+	return func(o *topicOptions) {
+		o.durable = &durableConfig{
+			check: func(payload any) bool {
+				_, ok := payload.(T)
+				return ok
+			},
+			decode: func(codec Codec, data []byte) (any, error) {
+				var payload T
+				err := codec.Unmarshal(data, &payload)
+				return payload, err
+			},
+		}
+	}
+}
+
+// Retention limits the size and the age of the write-ahead log of a durable topic.
+// The oldest segments of the log are deleted when the total size exceeds maxBytes,
+// or when their last message is older than maxAge. Zero means no limit.
+// The limits are enforced when the topic is opened and when a segment is full,
+// and the messages deleted are lost even if they have not been dispatched.
+func Retention(maxBytes int64, maxAge time.Duration) TopicOption {
+	return func(o *topicOptions) {
+		o.retentionBytes = maxBytes
+		o.retentionAge = maxAge
+	}
+}
+
+// WithDataDir sets the directory storing the write-ahead logs of the durable topics.
+// Each durable topic is stored in a subdirectory named after the topic.
+func WithDataDir(dir string) Option {
+	return func(o *options) {
+		o.dataDir = dir
+	}
+}
+
+// WithSegmentSize sets the size above which a new segment of the write-ahead log of a durable topic is started.
+// The default size is 16 MiB.
+func WithSegmentSize(size int64) Option {
+	return func(o *options) {
+		o.segmentSize = size
+	}
+}
+
+// WithFsync flushes every message published to a durable topic to the disk before returning,
+// so that it survives a power failure, not only a crash of the process.
+func WithFsync() Option {
+	return func(o *options) {
+		o.fsync = true
+	}
+}
+
+// topicDir returns the name of the directory storing a durable topic.
+func topicDir(topic string) string {
+	return escapeFileName(topic) + topicDirExt
+}
+
+// escapeFileName escapes a name so that any name is a valid and distinct file name.
+func escapeFileName(name string) string {
+	return strings.ReplaceAll(url.PathEscape(name), ".", "%2E")
+}
+
+// durableLog is the write-ahead log of a durable topic, along with the position of its dispatcher.
+type durableLog struct {
+	wal   *wal
+	codec Codec
+	// reader reads the messages to dispatch.
+	reader *walReader
+	// checkpoint stores the sequence number of the last message dispatched.
+	checkpoint *offsetFile
+	dispatched atomic.Uint64
+	// notify wakes up the dispatcher when there may be messages to dispatch.
+	notify chan struct{}
+	// subscribers are the durable subscribers of the topic, indexed by name.
+	subscribers *CowMap
+	// dir is the directory of the write-ahead log.
+	dir string
+}
+
+// openDurable opens the write-ahead log of a durable topic.
+func (c *channel) openDurable() error {
+	if c.opts.dataDir == "" {
+		return ErrNoDataDir
+	}
+	cfg := walConfig{
+		segmentSize: c.opts.segmentSize,
+		maxBytes:    c.config.retentionBytes,
+		maxAge:      c.config.retentionAge,
+		fsync:       c.opts.fsync,
+	}
+	if cfg.segmentSize <= 0 {
+		cfg.segmentSize = defaultSegmentSize
+	}
+	dir := filepath.Join(c.opts.dataDir, topicDir(c.topic))
+	w, err := openWAL(dir, cfg)
+	if err != nil {
+		return err
+	}
+	checkpoint, dispatched, err := openOffset(filepath.Join(dir, dispatchedOffsetFile), c.opts.fsync)
+	if err != nil {
+		w.close()
+		return err
+	}
+
+	d := &durableLog{
+		wal:         w,
+		codec:       c.opts.codecOrDefault(),
+		reader:      w.reader(dispatched + 1),
+		checkpoint:  checkpoint,
+		notify:      make(chan struct{}, 1),
+		subscribers: NewCowMap(),
+		dir:         dir,
+	}
+	d.dispatched.Store(dispatched)
+	c.durable = d
+	return nil
+}
+
+// pending returns the number of messages of the write-ahead log which have not been dispatched yet.
+func (d *durableLog) pending() int {
+	dispatched := d.dispatched.Load()
+	last := d.wal.lastSeq()
+	if last < dispatched {
+		return 0
+	}
+	return int(last - dispatched)
+}
+
+// signal wakes up the dispatcher and the durable subscribers of the durable topic.
+func (d *durableLog) signal() {
+	notify(d.notify)
+	d.notifySubscribers()
+}
+
+// notifySubscribers wakes up the durable subscribers.
+func (d *durableLog) notifySubscribers() {
+	d.subscribers.Range(func(key any, s any) bool {
+		notify(s.(*durableSubscriber).notify)
+		return true
+	})
+}
+
+// notify sends a signal to a channel with a buffer of one, unless a signal is already pending.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// close closes the write-ahead log. It must be called once all its readers have exited.
+func (d *durableLog) close() {
+	d.reader.close()
+	d.checkpoint.close()
+	d.wal.close()
+}
+
+// append stores a message in the write-ahead log, and sets its sequence number.
+func (d *durableLog) append(m *message) error {
+	payload, err := d.codec.Marshal(m.payload)
+	if err != nil {
+		return err
+	}
+	r := &walRecord{
+		time:    m.time,
+		id:      m.id,
+		source:  m.source,
+		headers: m.headers,
+		payload: payload,
+	}
+	if err := d.wal.append(r); err != nil {
+		return err
+	}
+	m.seq = r.seq
+	return nil
+}
+
+// durableMessage decodes a message stored in the write-ahead log of the topic.
+func (c *channel) durableMessage(r *walRecord) (*message, error) {
+	payload, err := c.config.durable.decode(c.durable.codec, r.payload)
+	if err != nil {
+		return nil, err
+	}
+	return &message{
+		id:      r.id,
+		time:    r.time,
+		topic:   c.topic,
+		seq:     r.seq,
+		headers: r.headers,
+		source:  r.source,
+		payload: payload,
+	}, nil
+}
+
+// publishDurable appends a message to the write-ahead log of the topic, then dispatches it
+// synchronously or wakes up the dispatcher, and wakes up the durable subscribers. The caller must hold the read lock.
+func (c *channel) publishDurable(m *message, sync bool) error {
+	if err := c.durable.append(m); err != nil {
+		c.drop(m.payload, err)
+		return err
+	}
+	if sync {
+		c.dispatchDurable(true)
+		c.durable.notifySubscribers()
+		return nil
+	}
+	c.durable.signal()
+	return nil
+}
+
+// dispatchDurable delivers the messages of the write-ahead log which have not been dispatched yet.
+// The messages are kept in the log while the topic has no handlers. sync is true when called by PublishSync().
+func (c *channel) dispatchDurable(sync bool) {
+	c.dispatchMu.Lock()
+	defer c.dispatchMu.Unlock()
+	d := c.durable
+	for c.subscriberCount() > 0 && !c.stopped() {
+		r, err := d.reader.next()
+		if err != nil {
+			c.logDurableError("eventbus: failed to read the write-ahead log", err)
+			if err == ErrCorruptedLog {
+				// The rest of the segment is skipped.
+				continue
+			}
+			return
+		}
+		if r == nil {
+			return
+		}
+		m, err := c.durableMessage(r)
+		if err != nil {
+			c.drop(nil, err)
+		} else {
+			c.deliver(m, sync)
+		}
+		d.dispatched.Store(r.seq)
+		if err := d.checkpoint.store(r.seq); err != nil {
+			c.logDurableError("eventbus: failed to store the dispatched offset", err)
+		}
+	}
+}
+
+// subscribeDurableFrom adds a handler to a durable topic, and delivers to the handler the messages
+// of the write-ahead log which have already been dispatched, starting from seq.
+// The messages not dispatched yet are then delivered by the dispatcher to all the handlers.
+func (c *channel) subscribeDurableFrom(seq uint64, h *handler) error {
+	c.RLock()
+	defer c.RUnlock()
+	if c.closed {
+		return ErrChannelClosed
+	}
+	c.touch()
+	defer c.durable.signal()
+
+	c.dispatchMu.Lock()
+	defer c.dispatchMu.Unlock()
+	c.handlers.Store(h.fn.Pointer(), h)
+	dispatched := c.durable.dispatched.Load()
+	if seq > dispatched {
+		return nil
+	}
+	reader := c.durable.wal.reader(seq)
+	defer reader.close()
+	for {
+		r, err := reader.next()
+		if err != nil {
+			c.logDurableError("eventbus: failed to read the write-ahead log", err)
+			if err == ErrCorruptedLog {
+				continue
+			}
+			return err
+		}
+		if r == nil || r.seq > dispatched {
+			return nil
+		}
+		m, err := c.durableMessage(r)
+		if err != nil {
+			c.drop(nil, err)
+			continue
+		}
+		c.call(m.context(c.opts.propagator), h, m)
+	}
+}
+
+// durableLoop dispatches the messages of a durable topic until the channel is stopped.
+// The write-ahead log is closed once the loop and the durable subscribers have exited.
+func (c *channel) durableLoop() {
+	defer c.exit()
+	if c.durable == nil {
+		<-c.stopCh
+		return
+	}
+	// Deliver the messages not dispatched before the last shutdown.
+	c.durable.signal()
+	for {
+		select {
+		case <-c.durable.notify:
+			c.dispatchDurable(false)
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// logDurableError logs an error of the write-ahead log of the topic, if a logger is set.
+func (c *channel) logDurableError(msg string, err error) {
+	if c.opts.logger != nil {
+		c.opts.logger.Error(msg, slog.String("topic", c.topic), slog.Any("error", err))
+	}
+}
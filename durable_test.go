@@ -0,0 +1,178 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type durableEvent struct {
+	ID   int
+	Name string
+}
+
+func durableHandler(topic string, event durableEvent) {
+}
+
+func Test_TopicDir(t *testing.T) {
+	assert.Equal(t, "orders.topic", topicDir("orders"))
+	assert.Equal(t, "a%2Fb.topic", topicDir("a/b"))
+	assert.Equal(t, "%2E%2E.topic", topicDir(".."))
+	assert.Equal(t, ".topic", topicDir(""))
+}
+
+func Test_DurableErrors(t *testing.T) {
+	bus := NewWithOptions(WithTopic("orders", Durable[durableEvent]()))
+	err := bus.Publish("orders", durableEvent{ID: 1})
+	assert.Equal(t, ErrNoDataDir, err)
+	err = bus.Subscribe("orders", busHandlerOne)
+	assert.Equal(t, ErrNoDataDir, err)
+	assert.Empty(t, bus.Topics())
+	bus.Close()
+
+	bus = NewWithOptions(WithDataDir(t.TempDir()), WithTopic("orders", Durable[durableEvent]()))
+	err = bus.Publish("orders", 1)
+	assert.Equal(t, ErrPayloadType, err)
+	bus.Close()
+}
+
+func Test_DurablePublish(t *testing.T) {
+	dir := t.TempDir()
+	bus := NewWithOptions(WithDataDir(dir), WithTopic("orders", Durable[durableEvent]()), WithSource("test"))
+
+	var mu sync.Mutex
+	var received []*Envelope[durableEvent]
+	err := bus.Subscribe("orders", func(env *Envelope[durableEvent]) {
+		mu.Lock()
+		received = append(received, env)
+		mu.Unlock()
+	})
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("orders", durableEvent{ID: 1, Name: "one"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, durableEvent{ID: 1, Name: "one"}, received[0].Payload)
+	assert.Equal(t, uint64(1), received[0].Seq)
+	assert.Equal(t, "test", received[0].Source)
+	assert.Equal(t, "orders", received[0].Topic)
+	assert.NotEmpty(t, received[0].ID)
+
+	err = bus.PublishEnvelope(&Envelope[durableEvent]{
+		Topic:   "orders",
+		Headers: map[string]string{"key": "value"},
+		Payload: durableEvent{ID: 2},
+	})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(2), received[1].Seq)
+	assert.Equal(t, "value", received[1].Headers["key"])
+	assert.Equal(t, 0, bus.QueueDepth("orders"))
+	bus.Close()
+}
+
+func Test_DurableRestart(t *testing.T) {
+	dir := t.TempDir()
+	opts := []Option{WithDataDir(dir), WithTopic("orders", Durable[durableEvent]())}
+	bus := NewWithOptions(opts...)
+
+	// The messages are kept while the topic has no handlers.
+	for i := 1; i <= 5; i++ {
+		err := bus.Publish("orders", durableEvent{ID: i})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 5, bus.QueueDepth("orders"))
+	var received []int
+	err := bus.Subscribe("orders", func(topic string, event durableEvent) {
+		received = append(received, event.ID)
+		if event.ID == 2 {
+			// Simulate a restart while messages are not dispatched yet.
+			go bus.Close()
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return bus.isClosed()
+	}, time.Second, time.Millisecond)
+	bus.Close()
+	assert.Equal(t, []int{1, 2}, received)
+
+	bus = NewWithOptions(opts...)
+	err = bus.Publish("orders", durableEvent{ID: 6})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, bus.QueueDepth("orders"))
+
+	var mu sync.Mutex
+	var replayed []uint64
+	err = bus.Subscribe("orders", func(env *Envelope[durableEvent]) {
+		mu.Lock()
+		replayed = append(replayed, env.Seq)
+		mu.Unlock()
+		assert.Equal(t, int(env.Seq), env.Payload.ID)
+	})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(replayed) == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []uint64{3, 4, 5, 6}, replayed)
+	bus.Close()
+}
+
+func Test_DurableSubscribeFrom(t *testing.T) {
+	dir := t.TempDir()
+	opts := []Option{WithDataDir(dir), WithSegmentSize(100), WithTopic("orders", Durable[durableEvent]())}
+	bus := NewWithOptions(opts...)
+	err := bus.Subscribe("orders", durableHandler)
+	assert.Nil(t, err)
+	for i := 1; i <= 10; i++ {
+		err = bus.PublishSync("orders", durableEvent{ID: i})
+		assert.Nil(t, err)
+	}
+	bus.Close()
+
+	bus = NewWithOptions(opts...)
+	var received []int
+	err = bus.SubscribeFrom("orders", 7, func(topic string, event durableEvent) {
+		received = append(received, event.ID)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{7, 8, 9, 10}, received)
+	err = bus.PublishSync("orders", durableEvent{ID: 11})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{7, 8, 9, 10, 11}, received)
+	bus.Close()
+}
+
+func Test_DurableRetention(t *testing.T) {
+	dir := t.TempDir()
+	bus := NewWithOptions(
+		WithDataDir(dir),
+		WithSegmentSize(100),
+		WithFsync(),
+		WithTopic("orders", Durable[durableEvent](), Retention(300, 0)),
+	)
+	err := bus.Subscribe("orders", durableHandler)
+	assert.Nil(t, err)
+	for i := 1; i <= 50; i++ {
+		err = bus.PublishSync("orders", durableEvent{ID: i})
+		assert.Nil(t, err)
+	}
+
+	var received []int
+	err = bus.SubscribeFrom("orders", 0, func(topic string, event durableEvent) {
+		received = append(received, event.ID)
+	})
+	assert.Nil(t, err)
+	assert.Greater(t, received[0], 1)
+	assert.Equal(t, 50, received[len(received)-1])
+	bus.Close()
+}
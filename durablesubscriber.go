@@ -0,0 +1,229 @@
+package eventbus
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+const (
+	// defaultRedeliveryDelay is the default delay before redelivering a message to a durable subscriber.
+	defaultRedeliveryDelay = time.Second
+	// subscribersDir is the directory storing the offsets of the durable subscribers of a topic.
+	subscribersDir = "subscribers"
+	// offsetFileExt is the extension of the offset files of the durable subscribers.
+	offsetFileExt = ".offset"
+)
+
+// WithRedeliveryDelay sets the delay before redelivering a message to a durable subscriber
+// whose handler failed. The default delay is one second.
+func WithRedeliveryDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.redeliveryDelay = delay
+	}
+}
+
+// durableSubscriber is a named handler of a durable topic, whose position in the
+// write-ahead log is stored on disk so that it resumes where it left off after a restart.
+type durableSubscriber struct {
+	name    string
+	handler *handler
+	reader  *walReader
+	// offset stores the sequence number of the last message acknowledged by the handler.
+	offset *offsetFile
+	notify chan struct{}
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// SubscribeDurable subscribes a named handler to a durable topic, with at-least-once delivery.
+// The handler receives the messages of the write-ahead log after the last message it acknowledged,
+// even across restarts, or from the oldest message of the log for a new name.
+// A message is acknowledged when the handler returns without error. If the handler returns an error
+// or panics, the message is redelivered after the delay set with WithRedeliveryDelay(), and the
+// following messages wait. A panic is only recovered if a panic handler is set with WithPanicHandler(),
+// otherwise the message is redelivered after the restart of the process.
+//
+// Each durable subscriber receives the messages from its own goroutine, independently of the other handlers.
+// The handler must follow the signatures supported by Subscribe(), and return an error to report failures.
+// Returns ErrDurableDelivery if the handler receives a *Delivery[T], ErrNoHistory if the topic is not durable,
+// and ErrSubscriberExists if a durable subscriber with the same name is already subscribed to the topic.
+func (e *EventBus) SubscribeDurable(topic string, name string, handler any) error {
+	h, err := newHandler(reflect.ValueOf(handler))
+	if err != nil {
+		return err
+	}
+	if h.delivery {
+		return ErrDurableDelivery
+	}
+	if e.opts.topicOptions(topic).durable == nil {
+		return ErrNoHistory
+	}
+
+	return e.withChannel(topic, func(ch *channel) error {
+		return ch.subscribeDurable(name, h)
+	})
+}
+
+// UnsubscribeDurable stops a durable subscriber of a topic, and waits for its handler to return.
+// Its offset is kept, so subscribing again with the same name resumes where it left off.
+// It must not be called from within the handler of the durable subscriber.
+// Returns ErrNoSubscriber if there is no such durable subscriber.
+func (e *EventBus) UnsubscribeDurable(topic string, name string) error {
+	ch, ok := e.channels.Load(topic)
+	if !ok || ch.(*channel).durable == nil {
+		return ErrNoSubscriber
+	}
+	return ch.(*channel).unsubscribeDurable(name)
+}
+
+// subscribeDurable starts a durable subscriber of the channel.
+func (c *channel) subscribeDurable(name string, h *handler) error {
+	c.RLock()
+	defer c.RUnlock()
+	if c.closed {
+		return ErrChannelClosed
+	}
+	c.touch()
+
+	d := c.durable
+	dir := filepath.Join(d.dir, subscribersDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	offset, acked, err := openOffset(filepath.Join(dir, escapeFileName(name)+offsetFileExt), c.opts.fsync)
+	if err != nil {
+		return err
+	}
+	s := &durableSubscriber{
+		name:    name,
+		handler: h,
+		reader:  d.wal.reader(acked + 1),
+		offset:  offset,
+		notify:  make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	if _, loaded := d.subscribers.LoadOrStore(name, s); loaded {
+		offset.close()
+		return ErrSubscriberExists
+	}
+
+	// The channel is not closed, so its loop is running and the count can't drop to zero meanwhile.
+	c.running.Add(1)
+	go c.runDurableSubscriber(s)
+	return nil
+}
+
+// unsubscribeDurable stops a durable subscriber of the channel and waits for it to exit.
+func (c *channel) unsubscribeDurable(name string) error {
+	s, ok := c.durable.subscribers.LoadAndDelete(name)
+	if !ok {
+		return ErrNoSubscriber
+	}
+	close(s.(*durableSubscriber).stopCh)
+	<-s.(*durableSubscriber).doneCh
+	return nil
+}
+
+// runDurableSubscriber delivers the messages of the write-ahead log to a durable subscriber,
+// until the subscriber or the channel is stopped.
+func (c *channel) runDurableSubscriber(s *durableSubscriber) {
+	defer c.exit()
+	defer close(s.doneCh)
+	defer s.reader.close()
+	defer s.offset.close()
+
+	for {
+		r, err := s.reader.next()
+		if err != nil {
+			c.logDurableError("eventbus: failed to read the write-ahead log", err)
+			// The rest of a corrupted segment is skipped, the other errors are retried later.
+			if err != ErrCorruptedLog && !s.sleep(c, c.redeliveryDelay()) {
+				return
+			}
+			continue
+		}
+		if r == nil {
+			if !s.waitMessages(c) {
+				return
+			}
+			continue
+		}
+
+		m, err := c.durableMessage(r)
+		if err != nil {
+			c.drop(nil, err)
+		} else if !c.deliverDurable(s, m) {
+			return
+		}
+		if err := s.offset.store(r.seq); err != nil {
+			c.logDurableError("eventbus: failed to store the offset of a durable subscriber", err)
+		}
+	}
+}
+
+// deliverDurable calls the handler of a durable subscriber with the message until it succeeds.
+// It reports false if the subscriber or the channel has been stopped before.
+func (c *channel) deliverDurable(s *durableSubscriber, m *message) bool {
+	ctx := m.context(c.opts.propagator)
+	for {
+		if s.stopped(c) {
+			return false
+		}
+		if err := c.call(ctx, s.handler, m); err == nil {
+			return true
+		}
+		if !s.sleep(c, c.redeliveryDelay()) {
+			return false
+		}
+	}
+}
+
+// redeliveryDelay returns the delay before redelivering a message to a durable subscriber.
+func (c *channel) redeliveryDelay() time.Duration {
+	if c.opts.redeliveryDelay > 0 {
+		return c.opts.redeliveryDelay
+	}
+	return defaultRedeliveryDelay
+}
+
+// waitMessages waits for new messages to be appended to the write-ahead log.
+// It reports false if the subscriber or the channel has been stopped meanwhile.
+func (s *durableSubscriber) waitMessages(c *channel) bool {
+	select {
+	case <-s.notify:
+		return true
+	case <-s.stopCh:
+		return false
+	case <-c.stopCh:
+		return false
+	}
+}
+
+// sleep waits for delay. It reports false if the subscriber or the channel has been stopped meanwhile.
+func (s *durableSubscriber) sleep(c *channel, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopCh:
+		return false
+	case <-c.stopCh:
+		return false
+	}
+}
+
+// stopped reports whether the subscriber or the channel has been stopped.
+func (s *durableSubscriber) stopped(c *channel) bool {
+	select {
+	case <-s.stopCh:
+		return true
+	case <-c.stopCh:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,210 @@
+package eventbus
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SubscribeDurableErrors(t *testing.T) {
+	bus := NewWithOptions(WithDataDir(t.TempDir()), WithTopic("orders", Durable[durableEvent]()))
+	err := bus.SubscribeDurable("events", "billing", durableHandler)
+	assert.Equal(t, ErrNoHistory, err)
+	err = bus.SubscribeDurable("orders", "billing", 1)
+	assert.Equal(t, ErrHandlerIsNotFunc, err)
+	err = bus.SubscribeDurable("orders", "billing", func(d *Delivery[durableEvent]) {})
+	assert.Equal(t, ErrDurableDelivery, err)
+
+	err = bus.SubscribeDurable("orders", "billing", durableHandler)
+	assert.Nil(t, err)
+	err = bus.SubscribeDurable("orders", "billing", durableHandler)
+	assert.Equal(t, ErrSubscriberExists, err)
+
+	err = bus.UnsubscribeDurable("orders", "shipping")
+	assert.Equal(t, ErrNoSubscriber, err)
+	err = bus.UnsubscribeDurable("events", "billing")
+	assert.Equal(t, ErrNoSubscriber, err)
+	err = bus.UnsubscribeDurable("orders", "billing")
+	assert.Nil(t, err)
+	err = bus.SubscribeDurable("orders", "billing", durableHandler)
+	assert.Nil(t, err)
+	bus.Close()
+}
+
+func Test_SubscribeDurableResume(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var received []int
+	handler := func(topic string, event durableEvent) {
+		mu.Lock()
+		received = append(received, event.ID)
+		mu.Unlock()
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received)
+	}
+
+	bus := NewWithOptions(WithDataDir(dir), WithTopic("orders", Durable[durableEvent]()))
+	for i := 1; i <= 3; i++ {
+		assert.Nil(t, bus.Publish("orders", durableEvent{ID: i}))
+	}
+	// A new durable subscriber starts from the oldest message of the log.
+	err := bus.SubscribeDurable("orders", "billing", handler)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return count() == 3 }, time.Second, time.Millisecond)
+	assert.Nil(t, bus.UnsubscribeDurable("orders", "billing"))
+
+	// The messages published meanwhile are delivered once subscribed again.
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 4}))
+	assert.Nil(t, bus.SubscribeDurable("orders", "billing", handler))
+	assert.Eventually(t, func() bool { return count() == 4 }, time.Second, time.Millisecond)
+	bus.Close()
+
+	bus = NewWithOptions(WithDataDir(dir), WithTopic("orders", Durable[durableEvent]()))
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 5}))
+	assert.Nil(t, bus.SubscribeDurable("orders", "billing", handler))
+	assert.Eventually(t, func() bool { return count() == 5 }, time.Second, time.Millisecond)
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 6}))
+	assert.Eventually(t, func() bool { return count() == 6 }, time.Second, time.Millisecond)
+	bus.Close()
+
+	mu.Lock()
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, received)
+	mu.Unlock()
+}
+
+func Test_SubscribeDurablePublishSync(t *testing.T) {
+	bus := NewWithOptions(WithDataDir(t.TempDir()), WithTopic("orders", Durable[durableEvent]()))
+	var received atomic.Int32
+	err := bus.SubscribeDurable("orders", "billing", func(topic string, event durableEvent) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	// The durable subscriber waits for new messages once it has received the first one.
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 1}))
+	assert.Eventually(t, func() bool { return received.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	// The durable subscribers are woken up by the messages published synchronously.
+	for i := 2; i <= 3; i++ {
+		assert.Nil(t, bus.PublishSync("orders", durableEvent{ID: i}))
+	}
+	assert.Eventually(t, func() bool { return received.Load() == 3 }, time.Second, time.Millisecond)
+	bus.Close()
+}
+
+func Test_SubscribeDurableRedelivery(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var received []int
+	failures := 2
+	handler := func(topic string, event durableEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event.ID)
+		if event.ID == 1 && failures > 0 {
+			failures--
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	bus := NewWithOptions(WithDataDir(dir), WithRedeliveryDelay(time.Millisecond),
+		WithTopic("orders", Durable[durableEvent]()))
+	assert.Nil(t, bus.SubscribeDurable("orders", "billing", handler))
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 1}))
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 2}))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 4
+	}, time.Second, time.Millisecond)
+	bus.Close()
+
+	mu.Lock()
+	assert.Equal(t, []int{1, 1, 1, 2}, received)
+	mu.Unlock()
+}
+
+func Test_SubscribeDurablePanic(t *testing.T) {
+	var panics int
+	var mu sync.Mutex
+	var received []int
+	handler := func(topic string, event durableEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event.ID)
+		if len(received) == 1 {
+			panic("boom")
+		}
+	}
+
+	bus := NewWithOptions(
+		WithDataDir(t.TempDir()),
+		WithRedeliveryDelay(time.Millisecond),
+		WithPanicHandler(func(topic string, err *PanicError) {
+			mu.Lock()
+			panics++
+			mu.Unlock()
+		}),
+		WithTopic("orders", Durable[durableEvent]()),
+	)
+	assert.Nil(t, bus.SubscribeDurable("orders", "billing", handler))
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 1}))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, time.Second, time.Millisecond)
+	bus.Close()
+
+	mu.Lock()
+	assert.Equal(t, []int{1, 1}, received)
+	assert.Equal(t, 1, panics)
+	mu.Unlock()
+}
+
+func Test_SubscribeDurableUnacked(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var received []int
+	failing := func(topic string, event durableEvent) error {
+		mu.Lock()
+		received = append(received, event.ID)
+		mu.Unlock()
+		return errors.New("failed")
+	}
+
+	bus := NewWithOptions(WithDataDir(dir), WithTopic("orders", Durable[durableEvent]()))
+	assert.Nil(t, bus.Publish("orders", durableEvent{ID: 1}))
+	assert.Nil(t, bus.SubscribeDurable("orders", "billing", failing))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	}, time.Second, time.Millisecond)
+	bus.Close()
+
+	// The message has not been acknowledged, so it is delivered again after a restart.
+	var redelivered []int
+	bus = NewWithOptions(WithDataDir(dir), WithTopic("orders", Durable[durableEvent]()))
+	assert.Nil(t, bus.SubscribeDurable("orders", "billing", func(topic string, event durableEvent) {
+		mu.Lock()
+		redelivered = append(redelivered, event.ID)
+		mu.Unlock()
+	}))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(redelivered) == 1
+	}, time.Second, time.Millisecond)
+	bus.Close()
+	assert.Equal(t, []int{1}, redelivered)
+}
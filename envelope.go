@@ -0,0 +1,146 @@
+package eventbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Envelope carries the payload of a message along with its metadata.
+// Handlers of the form func(env *Envelope[T]) receive the messages wrapped in an envelope,
+// and PublishEnvelope() publishes a message with custom metadata.
+type Envelope[T any] struct {
+	// ID is the unique identifier of the message, generated by the eventbus if empty.
+	ID string
+	// Time is the time the message was published, set by the eventbus if zero.
+	Time time.Time
+	// Topic is the topic the message is published to.
+	Topic string
+	// Seq is the sequence number of the message within its topic, starting at 1.
+	// It is set by the eventbus when the message is dispatched, and ignored by PublishEnvelope().
+	Seq uint64
+	// Headers are arbitrary key-value pairs attached to the message.
+	// The map is shared by all the handlers of the message, so it must not be modified.
+	Headers map[string]string
+	// Source identifies the publisher of the message, see WithSource().
+	Source string
+	// Payload is the payload of the message.
+	Payload T
+}
+
+// AnyEnvelope is implemented by *Envelope[T] for every T,
+// so that envelopes of any payload type can be passed to PublishEnvelope().
+type AnyEnvelope interface {
+	toMessage() *message
+	fromMessage(m *message)
+}
+
+// toMessage converts the envelope to a message.
+func (e *Envelope[T]) toMessage() *message {
+	return &message{
+		id:      e.ID,
+		time:    e.Time,
+		topic:   e.Topic,
+		headers: e.Headers,
+		source:  e.Source,
+		payload: e.Payload,
+	}
+}
+
+// fromMessage fills the envelope with a message.
+// It panics if the payload of the message is not of type T.
+func (e *Envelope[T]) fromMessage(m *message) {
+	e.ID = m.id
+	e.Time = m.time
+	e.Topic = m.topic
+	e.Seq = m.seq
+	e.Headers = m.headers
+	e.Source = m.source
+	if m.payload != nil {
+		e.Payload = m.payload.(T)
+	}
+}
+
+// message is a payload travelling through the channel of a topic, along with its metadata.
+type message struct {
+	id      string
+	time    time.Time
+	topic   string
+	seq     uint64
+	headers map[string]string
+	source  string
+	payload any
+	// ctx is the context captured when the message was published, if any.
+	ctx context.Context
+	// publishCtx is the context given to PublishContext() or PublishSyncContext(), if any.
+	// Unlike ctx, it is never detached nor passed to the handlers, and only releases the publisher.
+	publishCtx context.Context
+	// enqueuedAt is the time the message was queued, used to measure the queue wait.
+	enqueuedAt time.Time
+}
+
+// fill sets the metadata of the message which have not been set by the publisher.
+func (m *message) fill(topic string, source string, now time.Time) {
+	m.topic = topic
+	if m.id == "" {
+		m.id = nextID()
+	}
+	if m.time.IsZero() {
+		m.time = now
+	}
+	if m.source == "" {
+		m.source = source
+	}
+}
+
+// inject writes ctx into a copy of its headers,
+// so that the headers given by the publisher are never modified.
+func (m *message) inject(ctx context.Context, propagator Propagator) {
+	headers := make(map[string]string, len(m.headers)+1)
+	for k, v := range m.headers {
+		headers[k] = v
+	}
+	propagator.Inject(ctx, headers)
+	m.headers = headers
+}
+
+// context returns the context to hand to the handlers of the message,
+// extracting the values propagated in the headers if a propagator is given.
+func (m *message) context(propagator Propagator) context.Context {
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if propagator != nil && len(m.headers) > 0 {
+		ctx = propagator.Extract(ctx, m.headers)
+	}
+	return ctx
+}
+
+var (
+	// idPrefix is a random prefix making the message IDs unique across processes.
+	idPrefix = newIDPrefix()
+	// idCounter makes the message IDs unique within the process.
+	idCounter atomic.Uint64
+)
+
+// newIDPrefix returns a random prefix for the message IDs.
+func newIDPrefix() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// nextID returns a new unique message ID.
+func nextID() string {
+	buf := make([]byte, 0, len(idPrefix)+17)
+	buf = append(buf, idPrefix...)
+	buf = append(buf, '-')
+	buf = strconv.AppendUint(buf, idCounter.Add(1), 16)
+	return string(buf)
+}
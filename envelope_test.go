@@ -0,0 +1,106 @@
+package eventbus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_nextID(t *testing.T) {
+	first := nextID()
+	second := nextID()
+	assert.NotEqual(t, first, second)
+	assert.True(t, strings.HasPrefix(first, idPrefix+"-"))
+}
+
+func Test_messageFill(t *testing.T) {
+	now := time.Now()
+	m := &message{payload: 1}
+	m.fill("testtopic", "source", now)
+	assert.Equal(t, "testtopic", m.topic)
+	assert.NotEmpty(t, m.id)
+	assert.Equal(t, now, m.time)
+	assert.Equal(t, "source", m.source)
+
+	at := now.Add(-time.Hour)
+	m = &message{id: "id", time: at, source: "publisher"}
+	m.fill("testtopic", "source", now)
+	assert.Equal(t, "id", m.id)
+	assert.Equal(t, at, m.time)
+	assert.Equal(t, "publisher", m.source)
+}
+
+func Test_EnvelopeConversion(t *testing.T) {
+	env := &Envelope[string]{
+		ID:      "id",
+		Time:    time.Now(),
+		Topic:   "testtopic",
+		Headers: map[string]string{"key": "value"},
+		Source:  "source",
+		Payload: "payload",
+	}
+	var copied Envelope[string]
+	copied.fromMessage(env.toMessage())
+	assert.Equal(t, *env, copied)
+}
+
+func Test_EventBusEnvelopeHandler(t *testing.T) {
+	bus := NewWithOptions(WithSource("tester"))
+	var envs []*Envelope[int]
+	err := bus.Subscribe("testtopic", func(env *Envelope[int]) {
+		envs = append(envs, env)
+	})
+	assert.Nil(t, err)
+	var plain []int
+	err = bus.Subscribe("testtopic", func(topic string, val int) {
+		plain = append(plain, val)
+	})
+	assert.Nil(t, err)
+
+	before := time.Now()
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	err = bus.PublishEnvelopeSync(&Envelope[int]{
+		ID:      "custom",
+		Topic:   "testtopic",
+		Headers: map[string]string{"key": "value"},
+		Payload: 2,
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []int{1, 2}, plain)
+	assert.Equal(t, 2, len(envs))
+	assert.NotEmpty(t, envs[0].ID)
+	assert.Equal(t, "testtopic", envs[0].Topic)
+	assert.Equal(t, "tester", envs[0].Source)
+	assert.False(t, envs[0].Time.Before(before))
+	assert.Nil(t, envs[0].Headers)
+	assert.Equal(t, 1, envs[0].Payload)
+
+	assert.Equal(t, "custom", envs[1].ID)
+	assert.Equal(t, "tester", envs[1].Source)
+	assert.Equal(t, map[string]string{"key": "value"}, envs[1].Headers)
+	assert.Equal(t, 2, envs[1].Payload)
+	bus.Close()
+}
+
+func Test_EventBusPublishEnvelope(t *testing.T) {
+	bus := New()
+	received := make(chan *Envelope[string], 1)
+	err := bus.Subscribe("testtopic", func(env *Envelope[string]) {
+		received <- env
+	})
+	assert.Nil(t, err)
+
+	err = bus.PublishEnvelope(&Envelope[string]{Topic: "testtopic", Source: "publisher", Payload: "hello"})
+	assert.Nil(t, err)
+	env := <-received
+	assert.Equal(t, "publisher", env.Source)
+	assert.Equal(t, "hello", env.Payload)
+	bus.Close()
+
+	err = bus.PublishEnvelope(&Envelope[string]{Topic: "testtopic"})
+	assert.Equal(t, ErrChannelClosed, err)
+}
@@ -4,6 +4,11 @@
 
 package eventbus
 
+import (
+	"fmt"
+	"strings"
+)
+
 type err struct {
 	Msg  string
 	Code int
@@ -22,9 +27,57 @@ func (e err) Error() string {
 // Global variables that represent common errors that may be
 // returned by the eventbus functions.
 var (
-	ErrHandlerIsNotFunc  = err{Code: 10000, Msg: "handler is not a function"}
-	ErrHandlerParamNum   = err{Code: 10001, Msg: "the number of parameters of the handler must be two"}
-	ErrHandlerFirstParam = err{Code: 10002, Msg: "the first of parameters of the handler must be a string"}
-	ErrNoSubscriber      = err{Code: 10003, Msg: "no subscriber on topic"}
-	ErrChannelClosed     = err{Code: 10004, Msg: "channel is closed"}
+	ErrHandlerIsNotFunc   = err{Code: 10000, Msg: "handler is not a function"}
+	ErrHandlerParamNum    = err{Code: 10001, Msg: "the handler must have two parameters, or a single *Envelope or *Delivery parameter"}
+	ErrHandlerFirstParam  = err{Code: 10002, Msg: "the first of parameters of the handler must be a string"}
+	ErrNoSubscriber       = err{Code: 10003, Msg: "no subscriber on topic"}
+	ErrChannelClosed      = err{Code: 10004, Msg: "channel is closed"}
+	ErrCloseTimeout       = err{Code: 10005, Msg: "close timed out while handlers are still running"}
+	ErrTopicNotFound      = err{Code: 10006, Msg: "topic not found"}
+	ErrInvalidTraceparent = err{Code: 10007, Msg: "invalid traceparent header"}
+	ErrQueueFull          = err{Code: 10008, Msg: "queue is full"}
+	ErrNoHistory          = err{Code: 10009, Msg: "topic has no history"}
+	ErrNoDataDir          = err{Code: 10010, Msg: "durable topics require a data directory"}
+	ErrPayloadType        = err{Code: 10011, Msg: "the type of the payload does not match the topic or the handler"}
+	ErrCorruptedLog       = err{Code: 10012, Msg: "corrupted write-ahead log record"}
+	ErrSubscriberExists   = err{Code: 10013, Msg: "durable subscriber already exists"}
+	ErrDeliverySettled    = err{Code: 10014, Msg: "delivery already settled"}
+	ErrNacked             = err{Code: 10015, Msg: "message rejected by the handler"}
+	ErrInvalidSchedule    = err{Code: 10016, Msg: "invalid schedule"}
+	ErrHandlerBatchParam  = err{Code: 10017, Msg: "the second parameter of a batch handler must be a slice"}
+	ErrDuplicate          = err{Code: 10018, Msg: "duplicate message"}
+	ErrRateLimited        = err{Code: 10019, Msg: "rate limit exceeded"}
+	ErrDurableDelivery    = err{Code: 10020, Msg: "durable subscribers acknowledge by returning, they can't receive a *Delivery"}
+	ErrThrottleEdges      = err{Code: 10021, Msg: "throttle edges must include ThrottleLeading or ThrottleTrailing"}
 )
+
+// CloseTimeoutError is returned when closing times out while some handlers are still running.
+// Topics lists the topics whose handlers did not return in time.
+type CloseTimeoutError struct {
+	Topics []string
+}
+
+// Error return the error's message
+func (e *CloseTimeoutError) Error() string {
+	if len(e.Topics) == 0 {
+		return ErrCloseTimeout.Msg
+	}
+	return ErrCloseTimeout.Msg + ": " + strings.Join(e.Topics, ", ")
+}
+
+// Unwrap returns ErrCloseTimeout, so that errors.Is(err, ErrCloseTimeout) reports true.
+func (e *CloseTimeoutError) Unwrap() error {
+	return ErrCloseTimeout
+}
+
+// PanicError is reported when a handler panics.
+// Value is the value recovered from the panic, and Stack is the stack trace of the handler's goroutine.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error return the error's message
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panicked: %v", e.Value)
+}
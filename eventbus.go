@@ -1,31 +1,70 @@
 package eventbus
 
 import (
+	"context"
 	"reflect"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// closeGrace is the time given to the idle channels to exit once the deadline of CloseTimeout() has expired.
+const closeGrace = 10 * time.Millisecond
+
 // channel is a struct representing a topic and its associated handlers.
 type channel struct {
 	sync.RWMutex
 	bufferSize int
 	topic      string
 	topicValue reflect.Value
-	channel    chan any
+	channel    chan *message
 	handlers   *CowMap
-	closed     bool
-	stopCh     chan struct{}
+	// groups are the consumer groups of the topic, indexed by name.
+	groups *CowMap
+	// pendingBatches are the batchers to flush once the channel is closed.
+	pendingBatches []*batcher
+	closed         bool
+	stopCh         chan struct{}
+	doneCh         chan struct{}
+	lastActive     atomic.Int64
+	config         *topicOptions
+	// dispatchMu serializes the deliveries of the stateful topics, so that new handlers
+	// receive the retained message or the history before the live messages.
+	dispatchMu sync.Mutex
+	retained   atomic.Pointer[message]
+	history    *history
+	dedup      *dedup
+	limiter    *tokenBucket
+	durable    *durableLog
+	// err is the error which prevented the channel from being created, if any.
+	err error
+	// seq is the sequence number of the last message dispatched, except for the durable topics
+	// whose messages are numbered by their write-ahead log.
+	seq atomic.Uint64
+	// running is the number of loop goroutines that have not exited yet.
+	running  atomic.Int32
+	metrics  *metrics
+	observer Observer
+	opts     *options
 }
 
 // newChannel creates a new channel with a specified topic and buffer size.
 // It initializes the handlers map with NewCowMap function and
-// starts a goroutine c.loop() to continuously listen to messages in the channel.
-func newChannel(topic string, bufferSize int) *channel {
-	var ch chan any
+// starts the c.loop() goroutines to continuously listen to messages in the channel.
+// The channel of a durable topic opens its write-ahead log, and sets c.err if it fails.
+// opts holds the options of the eventbus, nil means the default options.
+func newChannel(topic string, bufferSize int, opts *options) *channel {
+	if opts == nil {
+		opts = &options{}
+	}
+	var ch chan *message
 	if bufferSize <= 0 {
-		ch = make(chan any)
+		ch = make(chan *message)
 	} else {
-		ch = make(chan any, bufferSize)
+		ch = make(chan *message, bufferSize)
 	}
 	c := &channel{
 		topic:      topic,
@@ -33,84 +72,329 @@ func newChannel(topic string, bufferSize int) *channel {
 		bufferSize: bufferSize,
 		channel:    ch,
 		handlers:   NewCowMap(),
+		groups:     NewCowMap(),
 		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+		config:     opts.topicOptions(topic),
+		metrics:    opts.newMetrics(),
+		observer:   opts.observer,
+		opts:       opts,
+	}
+	if c.config.hasHistory() {
+		c.history = newHistory(c.config.historySize, c.config.historyAge)
+	}
+	if c.config.dedup {
+		c.dedup = newDedup(c.config.dedupSize, c.config.dedupAge)
+	}
+	if c.config.rate > 0 {
+		c.limiter = newTokenBucket(c.config.rate, c.config.burst, time.Now())
+	}
+	c.touch()
+	if c.config.durable != nil {
+		c.running.Store(1)
+		c.err = c.openDurable()
+		go c.durableLoop()
+		return c
+	}
+	workers := opts.workerCount()
+	c.running.Store(int32(workers))
+	for i := 0; i < workers; i++ {
+		go c.loop()
 	}
-	go c.loop()
 	return c
 }
 
-// transfer calls all the handlers in the channel with the given payload.
-// It iterates over the handlers in the handlers map to call them with the payload.
-func (c *channel) transfer(payload any) {
-	var payloadValue reflect.Value
+// touch records the current time as the last time the channel was used, and returns it.
+func (c *channel) touch() time.Time {
+	now := time.Now()
+	c.lastActive.Store(now.UnixNano())
+	return now
+}
 
-	c.handlers.Range(func(key any, fn any) bool {
-		handler := fn.(*reflect.Value)
-		typ := handler.Type()
+// transfer calls all the handlers in the channel with the given message.
+// The deliveries of the stateful topics are serialized.
+// sync is true if the message is published with PublishSync(), see deliver.
+func (c *channel) transfer(m *message, sync bool) {
+	if c.config.stateful() {
+		c.dispatchMu.Lock()
+		defer c.dispatchMu.Unlock()
+	}
+	c.deliver(m, sync)
+}
 
-		if payload == nil {
-			// If the parameter passed to the handler is nil,
-			// it initializes a new payload element based on the
-			// type of the second parameter of the handler using the reflect package.
-			payloadValue = reflect.New(typ.In(1)).Elem()
-		} else {
-			payloadValue = reflect.ValueOf(payload)
+// deliver iterates over the handlers in the handlers map to call them with the message.
+// The message is dropped if there are no handlers.
+// The message is retained or added to the history if the topic is configured to.
+// The members of the consumer groups are called from their own goroutines, unless sync is true:
+// the messages published with PublishSync() are delivered to them from the calling goroutine.
+// The caller must hold dispatchMu if the topic is stateful.
+func (c *channel) deliver(m *message, sync bool) {
+	if m.seq == 0 {
+		m.seq = c.seq.Add(1)
+	}
+	if c.config.retained {
+		c.retained.Store(m)
+	}
+	if c.history != nil {
+		c.history.add(m, time.Now())
+	}
+	ctx := m.context(c.opts.propagator)
+	delivered := c.deliverGroups(ctx, m, sync)
+	c.handlers.Range(func(key any, h any) bool {
+		switch h := h.(*handler); {
+		case h.pacer != nil:
+			h.pacer.offer(ctx, m)
+		case h.batcher != nil:
+			h.batcher.add(m)
+		default:
+			c.call(ctx, h, m)
 		}
-		(*handler).Call([]reflect.Value{c.topicValue, payloadValue})
+		delivered = true
 		return true
 	})
+	if !delivered {
+		c.drop(m.payload, ErrNoSubscriber)
+	}
+}
+
+// drop records a message discarded without being delivered to any handler.
+func (c *channel) drop(payload any, reason error) {
+	c.metrics.drop(1)
+	if c.observer != nil {
+		c.observer.OnDrop(c.topic, payload, reason)
+	}
+}
+
+// call calls a handler with the given message and records its metrics.
+// A handler whose last return value is a non-nil error is counted as failed.
+// Panics are counted and reported as a *PanicError, then passed to the panic handler
+// of the eventbus if any, or propagated to the caller otherwise.
+// It returns the error of the handler, or the *PanicError if the panic has been recovered.
+func (c *channel) call(ctx context.Context, h *handler, m *message) error {
+	return c.callAttempt(ctx, h, m, 1)
+}
+
+// callAttempt calls a handler like call. attempt is the number of times
+// the message has been delivered to a handler receiving a *Delivery[T].
+func (c *channel) callAttempt(ctx context.Context, h *handler, m *message, attempt int) (err error) {
+	if c.observer != nil {
+		c.observer.OnDeliverStart(c.topic, h.name, m.payload)
+	}
+	start := time.Now()
+	defer func() {
+		r := recover()
+		var panicErr *PanicError
+		if r != nil {
+			panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+			err = panicErr
+		}
+		duration := time.Since(start)
+		c.metrics.deliver(duration, r != nil, err)
+		if c.observer != nil {
+			c.observer.OnDeliverEnd(c.topic, h.name, m.payload, duration, err)
+		}
+		if r == nil {
+			return
+		}
+		if c.opts.panicHandler == nil {
+			panic(r)
+		}
+		c.opts.panicHandler(c.topic, panicErr)
+	}()
+
+	var a *ack
+	if h.delivery {
+		a = c.newAck(ctx, h, m, attempt)
+	}
+	return h.call(ctx, c.topicValue, m, a)
 }
 
 // loop listens to the channel and calls handlers with payload.
 // It receives messages from the channel and then iterates over the handlers
 // in the handlers map to call them with the payload.
+// doneCh is closed when the last loop goroutine exits.
 func (c *channel) loop() {
+	defer c.exit()
 	for {
 		select {
-		case payload := <-c.channel:
-			c.transfer(payload)
+		case m, ok := <-c.channel:
+			if !ok {
+				return
+			}
+			if c.stopped() {
+				c.drop(m.payload, ErrChannelClosed)
+				return
+			}
+			c.metrics.wait(time.Since(m.enqueuedAt))
+			c.transfer(m, false)
 		case <-c.stopCh:
 			return
 		}
 	}
 }
 
-// subscribe add a handler to a channel, return error if the channel is closed.
-func (c *channel) subscribe(handler any) error {
+// enter registers a new goroutine of the channel, unless all of them have already exited.
+// The goroutine must call exit when it exits.
+func (c *channel) enter() bool {
+	for {
+		n := c.running.Load()
+		if n == 0 {
+			return false
+		}
+		if c.running.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// exit is called by every goroutine of the channel when it exits.
+// The last one releases the resources of the channel and closes doneCh.
+func (c *channel) exit() {
+	if c.running.Add(-1) > 0 {
+		return
+	}
+	for _, b := range c.pendingBatches {
+		b.flush()
+	}
+	if c.durable != nil {
+		c.durable.close()
+	}
+	close(c.doneCh)
+}
+
+// stopped reports whether the channel has been stopped.
+// The messages remaining in the buffer of a stopped channel are discarded.
+func (c *channel) stopped() bool {
+	select {
+	case <-c.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribe add a handler to a channel, return error if the channel is closed
+// or if the signature of the handler is not supported.
+// If the topic retains messages, the retained message is delivered to the handler before returning.
+func (c *channel) subscribe(handler any, opts ...SubscribeOption) error {
+	h, err := newHandler(reflect.ValueOf(handler))
+	if err != nil {
+		return err
+	}
+	if h.pacer, err = c.newPacer(h, opts); err != nil {
+		return err
+	}
+
 	c.RLock()
 	defer c.RUnlock()
 	if c.closed {
 		return ErrChannelClosed
 	}
-	fn := reflect.ValueOf(handler)
-	c.handlers.Store(fn.Pointer(), &fn)
+	c.touch()
+	if c.durable != nil {
+		// Deliver the messages kept while the topic had no handlers.
+		defer c.durable.signal()
+	}
+	if !c.config.retained {
+		c.handlers.Store(h.fn.Pointer(), h)
+		return nil
+	}
+
+	c.dispatchMu.Lock()
+	defer c.dispatchMu.Unlock()
+	c.handlers.Store(h.fn.Pointer(), h)
+	if m := c.retained.Load(); m != nil {
+		c.call(m.context(c.opts.propagator), h, m)
+	}
 	return nil
 }
 
-// publishSync triggers the handlers defined for this channel synchronously.
-// The payload argument will be passed to the handler.
-// It does not use channels and instead directly calls the handler function.
-func (c *channel) publishSync(payload any) error {
+// subscribeFrom add a handler to a channel like subscribe, and delivers to the handler
+// the messages of the history whose sequence number is greater than or equal to seq before returning.
+// The history of a durable topic is read from its write-ahead log.
+// Returns ErrNoHistory if the topic does not keep a history.
+func (c *channel) subscribeFrom(seq uint64, handler any) error {
+	h, err := newHandler(reflect.ValueOf(handler))
+	if err != nil {
+		return err
+	}
+	if c.durable != nil {
+		return c.subscribeDurableFrom(seq, h)
+	}
+	if c.history == nil {
+		return ErrNoHistory
+	}
+
 	c.RLock()
 	defer c.RUnlock()
 	if c.closed {
 		return ErrChannelClosed
 	}
-	c.transfer(payload)
+	c.touch()
+
+	c.dispatchMu.Lock()
+	defer c.dispatchMu.Unlock()
+	c.handlers.Store(h.fn.Pointer(), h)
+	for _, m := range c.history.since(seq, time.Now()) {
+		c.call(m.context(c.opts.propagator), h, m)
+	}
 	return nil
 }
 
+// publishSync triggers the handlers defined for this channel synchronously.
+// The payload argument will be passed to the handler.
+// It does not use channels and instead directly calls the handler function.
+func (c *channel) publishSync(payload any) error {
+	return c.publishMessage(&message{payload: payload}, true)
+}
+
 // publish triggers the handlers defined for this channel asynchronously.
 // The `payload` argument will be passed to the handler.
 // It uses the channel to asynchronously call the handler.
 func (c *channel) publish(payload any) error {
+	return c.publishMessage(&message{payload: payload}, false)
+}
+
+// publishMessage fills the missing metadata of a message and triggers the handlers
+// defined for this channel, synchronously or asynchronously.
+// Messages published asynchronously to a full channel are handled according to the BackpressurePolicy.
+// Messages exceeding the rate limit of the topic are handled according to its RateLimitPolicy.
+func (c *channel) publishMessage(m *message, sync bool) error {
+	if ok, err := c.limit(m); !ok {
+		return err
+	}
 	c.RLock()
 	defer c.RUnlock()
 	if c.closed {
 		return ErrChannelClosed
 	}
-	c.channel <- payload
-	return nil
+	if c.durable != nil && !c.config.durable.check(m.payload) {
+		return ErrPayloadType
+	}
+	now := c.touch()
+	m.fill(c.topic, c.opts.source, now)
+	c.metrics.publish()
+	if c.observer != nil {
+		c.observer.OnPublish(c.topic, m.payload)
+	}
+	if c.duplicate(m, now) {
+		c.drop(m.payload, ErrDuplicate)
+		return nil
+	}
+	if c.durable != nil {
+		return c.publishDurable(m, sync)
+	}
+	if sync {
+		c.transfer(m, true)
+		return nil
+	}
+	m.enqueuedAt = now
+	return enqueue(c.channel, m, c.opts.backpressure, c.dropFull)
+}
+
+// dropFull drops a message discarded because the channel is full.
+func (c *channel) dropFull(m *message) {
+	c.drop(m.payload, ErrQueueFull)
 }
 
 // unsubscribe removes handler defined for this channel.
@@ -120,31 +404,145 @@ func (c *channel) unsubscribe(handler any) error {
 	if c.closed {
 		return ErrChannelClosed
 	}
+	c.touch()
 	fn := reflect.ValueOf(handler)
-	c.handlers.Delete(fn.Pointer())
+	if h, ok := c.handlers.LoadAndDelete(fn.Pointer()); ok {
+		flushRemoved(h)
+	}
 	return nil
 }
 
-// close closes a channel
-func (c *channel) close() {
+// subscriberCount returns the number of handlers of the channel, including the members of the consumer groups.
+func (c *channel) subscriberCount() int {
+	return int(c.handlers.Len()) + c.groupMembers()
+}
+
+// stop marks the channel as closed and signals the loop goroutine to exit,
+// without waiting for it.
+func (c *channel) stop() {
+	c.Lock()
+	defer c.Unlock()
+	c.shutdown()
+}
+
+// tryStop stops the channel like stop, unless its lock is held, typically by a publisher
+// blocked by a running handler. It reports whether the channel has been stopped.
+func (c *channel) tryStop() bool {
+	if !c.TryLock() {
+		return false
+	}
+	defer c.Unlock()
+	c.shutdown()
+	return true
+}
+
+// stopIfUnused stops the channel if it has no handlers and has not been used for at least idle.
+// The channels of stateful topics are never considered unused.
+// It reports whether the channel has been stopped by this call.
+func (c *channel) stopIfUnused(idle time.Duration) bool {
 	c.Lock()
 	defer c.Unlock()
+	if c.closed || c.config.stateful() || c.subscriberCount() > 0 {
+		return false
+	}
+	if time.Since(time.Unix(0, c.lastActive.Load())) < idle {
+		return false
+	}
+	c.shutdown()
+	return true
+}
+
+// shutdown marks the channel as closed and signals the loop goroutine to exit.
+// The caller must hold the write lock.
+func (c *channel) shutdown() {
 	if c.closed {
 		return
 	}
 	c.closed = true
+	// The batches in progress are delivered once the goroutines of the channel have exited.
+	c.pendingBatches = c.batchers()
 	close(c.stopCh)
 	c.handlers.Clear()
 	close(c.channel)
+	// The messages still in the buffer will never be delivered.
+	// The loop goroutine may receive some of them concurrently, and drops them as well.
+	for m := range c.channel {
+		c.drop(m.payload, ErrChannelClosed)
+	}
+	if c.observer != nil {
+		c.observer.OnTopicClosed(c.topic)
+	}
+}
+
+// wait blocks until the loop goroutines have exited or the timeout expires.
+// A timeout less than or equal to zero means waiting forever.
+// It reports whether the loop goroutines have exited.
+func (c *channel) wait(timeout time.Duration) bool {
+	if timeout <= 0 {
+		<-c.doneCh
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.doneCh:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// exited reports whether the loop goroutines have exited.
+func (c *channel) exited() bool {
+	select {
+	case <-c.doneCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// queueDepth returns the number of messages waiting to be delivered.
+func (c *channel) queueDepth() int {
+	if c.durable != nil {
+		return c.durable.pending()
+	}
+	return len(c.channel)
+}
+
+// discard closes a channel that has never been used, without notifying the observer.
+func (c *channel) discard() {
+	c.observer = nil
+	c.close()
+}
+
+// close closes a channel and waits for its loop goroutines to exit.
+func (c *channel) close() {
+	c.stop()
+	c.wait(0)
 }
 
 // EventBus is a container for event topics.
 // Each topic corresponds to a channel. `eventbus.Publish()` pushes a message to the channel,
 // and the handler in `eventbus.Subscribe()` will process the message coming out of the channel.
 type EventBus struct {
+	sync.RWMutex
 	channels   *CowMap
 	bufferSize int
-	once       sync.Once
+	opts       options
+	closed     bool
+	// createMu serializes the creation of the durable topics.
+	createMu sync.Mutex
+	// retired holds the channels removed from the eventbus whose loop goroutine may still be running.
+	retired *CowMap
+	// scheduler publishes the messages of PublishAfter() and PublishAt().
+	scheduler *scheduler
+	// jobs holds the periodic jobs started by Schedule() and Every().
+	jobs *CowMap
+	// jobsStopped is true once the periodic jobs have been stopped by Close().
+	jobsStopped bool
+	reaperCh    chan struct{}
+	reaperWg    sync.WaitGroup
 }
 
 // NewBuffered returns new EventBus with a buffered channel.
@@ -153,55 +551,135 @@ func NewBuffered(bufferSize int) *EventBus {
 	if bufferSize <= 0 {
 		bufferSize = 1
 	}
-	return &EventBus{
-		bufferSize: bufferSize,
-		channels:   NewCowMap(),
-	}
+	return NewWithOptions(WithBufferSize(bufferSize))
 }
 
 // New returns new EventBus with empty handlers.
 func New() *EventBus {
-	return &EventBus{
+	return NewWithOptions()
+}
+
+// NewWithOptions returns new EventBus configured by the given options.
+// Without options, it is equivalent to New().
+func NewWithOptions(opts ...Option) *EventBus {
+	e := &EventBus{
 		bufferSize: -1,
 		channels:   NewCowMap(),
+		opts:       newOptions(opts),
+		retired:    NewCowMap(),
+		jobs:       NewCowMap(),
 	}
+	e.scheduler = newScheduler(func(topic string, payload any, sync bool) {
+		var err error
+		if sync {
+			err = e.PublishSync(topic, payload)
+		} else {
+			err = e.Publish(topic, payload)
+		}
+		e.notifyPublishError(topic, payload, err)
+	})
+	if e.opts.bufferSize > 0 {
+		e.bufferSize = e.opts.bufferSize
+	}
+	if e.opts.idleTTL > 0 {
+		e.startReaper(e.opts.idleTTL)
+	}
+	return e
+}
+
+// loadOrCreate returns the channel of the topic, creating it if it does not exist yet.
+// Returns ErrChannelClosed if the eventbus has been closed.
+func (e *EventBus) loadOrCreate(topic string) (*channel, error) {
+	if ch, ok := e.channels.Load(topic); ok {
+		return ch.(*channel), nil
+	}
+
+	e.RLock()
+	defer e.RUnlock()
+	if e.closed {
+		return nil, ErrChannelClosed
+	}
+	if e.opts.topicOptions(topic).durable != nil {
+		// The write-ahead log of a durable topic must be opened only once.
+		e.createMu.Lock()
+		defer e.createMu.Unlock()
+		if ch, ok := e.channels.Load(topic); ok {
+			return ch.(*channel), nil
+		}
+	}
+	newCh := newChannel(topic, e.bufferSize, &e.opts)
+	if newCh.err != nil {
+		newCh.discard()
+		return nil, newCh.err
+	}
+	ch, loaded := e.channels.LoadOrStore(topic, newCh)
+	if loaded {
+		newCh.discard()
+	} else if e.opts.observer != nil {
+		e.opts.observer.OnTopicCreated(topic)
+	}
+	return ch.(*channel), nil
+}
+
+// withChannel calls fn with the channel of the topic, creating the channel if it does not exist yet.
+// If the channel is removed concurrently, fn is called again with a new channel.
+func (e *EventBus) withChannel(topic string, fn func(ch *channel) error) error {
+	for {
+		ch, err := e.loadOrCreate(topic)
+		if err != nil {
+			return err
+		}
+		err = fn(ch)
+		if err != ErrChannelClosed || e.isClosed() {
+			return err
+		}
+	}
+}
+
+// isClosed reports whether the eventbus has been closed.
+func (e *EventBus) isClosed() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.closed
 }
 
 // Unsubscribe removes handler defined for a topic.
 // Returns error if there are no handlers subscribed to the topic.
+// If the eventbus is created with WithAutoRemoveTopics(), the topic is removed
+// once its last handler unsubscribes.
 func (e *EventBus) Unsubscribe(topic string, handler any) error {
 	ch, ok := e.channels.Load(topic)
 	if !ok {
 		return ErrNoSubscriber
 	}
-	return ch.(*channel).unsubscribe(handler)
+	err := ch.(*channel).unsubscribe(handler)
+	if err == ErrChannelClosed && !e.isClosed() {
+		// The topic has been removed concurrently.
+		return ErrNoSubscriber
+	}
+	if err == nil && e.opts.autoRemove {
+		e.removeIfUnused(ch.(*channel), 0)
+	}
+	return err
 }
 
 // Subscribe subscribes to a topic, return an error if the handler is not a function.
 // The handler must have two parameters: the first parameter must be a string,
-// and the type of the handler's second parameter must be consistent with the type of the payload in `Publish()`
-func (e *EventBus) Subscribe(topic string, handler any) error {
-	typ := reflect.TypeOf(handler)
-	if typ.Kind() != reflect.Func {
-		return ErrHandlerIsNotFunc
-	}
-	if typ.NumIn() != 2 {
-		return ErrHandlerParamNum
-	}
-	if typ.In(0).Kind() != reflect.String {
-		return ErrHandlerFirstParam
+// and the type of the handler's second parameter must be consistent with the type of the payload in `Publish()`.
+// Alternatively, the handler may have a single *Envelope[T] parameter to receive the metadata of the messages,
+// T being consistent with the type of the payload.
+// A handler with a single *Delivery[T] parameter must acknowledge every message explicitly, see Delivery.
+// All the forms accept a leading context.Context parameter, receiving the context given to PublishContext().
+// If the last return value of the handler is an error, a non-nil error is reported as a failure.
+// The options WithDebounce() and WithThrottle() limit the rate of the messages delivered to the handler.
+func (e *EventBus) Subscribe(topic string, handler any, opts ...SubscribeOption) error {
+	if _, err := newHandler(reflect.ValueOf(handler)); err != nil {
+		return err
 	}
 
-	ch, ok := e.channels.Load(topic)
-	if !ok {
-		newCh := newChannel(topic, e.bufferSize)
-		ch, loaded := e.channels.LoadOrStore(topic, newCh)
-		if loaded {
-			newCh.close()
-		}
-		return ch.(*channel).subscribe(handler)
-	}
-	return ch.(*channel).subscribe(handler)
+	return e.withChannel(topic, func(ch *channel) error {
+		return ch.subscribe(handler, opts...)
+	})
 }
 
 // publish triggers the handlers defined for this channel asynchronously.
@@ -209,42 +687,177 @@ func (e *EventBus) Subscribe(topic string, handler any) error {
 // It uses the channel to asynchronously call the handler.
 // The type of the payload must correspond to the second parameter of the handler in `Subscribe()`.
 func (e *EventBus) Publish(topic string, payload any) error {
-	ch, ok := e.channels.Load(topic)
-	if !ok {
-		newCh := newChannel(topic, e.bufferSize)
-		var loaded bool
-		ch, loaded = e.channels.LoadOrStore(topic, newCh)
-		if loaded {
-			newCh.close()
-		}
-	}
-
-	return ch.(*channel).publish(payload)
+	return e.publish(&message{payload: payload}, topic, false)
 }
 
 // publishSync triggers the handlers defined for this channel synchronously.
 // The payload argument will be passed to the handler.
 // It does not use channels and instead directly calls the handler function.
 func (e *EventBus) PublishSync(topic string, payload any) error {
-	ch, ok := e.channels.Load(topic)
-	if !ok {
-		newCh := newChannel(topic, e.bufferSize)
-		var loaded bool
-		ch, loaded = e.channels.LoadOrStore(topic, newCh)
-		if loaded {
-			newCh.close()
+	return e.publish(&message{payload: payload}, topic, true)
+}
+
+// publish publishes a message to a topic, synchronously or asynchronously,
+// applying the NoSubscriberPolicy if the topic has no handlers.
+// The policy does not apply to the stateful topics.
+func (e *EventBus) publish(m *message, topic string, sync bool) error {
+	if e.opts.noSubscriber != NoSubscriberAccept && !e.opts.topicOptions(topic).stateful() && !e.HasSubscribers(topic) {
+		return e.unrouted(m, topic, sync)
+	}
+	return e.withChannel(topic, func(ch *channel) error {
+		return ch.publishMessage(m, sync)
+	})
+}
+
+// unrouted handles a message published to a topic without handlers,
+// according to the NoSubscriberPolicy of the eventbus.
+func (e *EventBus) unrouted(m *message, topic string, sync bool) error {
+	if e.isClosed() {
+		return ErrChannelClosed
+	}
+	switch e.opts.noSubscriber {
+	case NoSubscriberDrop:
+		e.notifyDrop(topic, m.payload)
+	case NoSubscriberError:
+		e.notifyDrop(topic, m.payload)
+		return ErrNoSubscriber
+	case NoSubscriberRoute:
+		// Messages are never routed twice, and are dropped if nobody listens to UnroutedTopic.
+		if topic == UnroutedTopic || !e.HasSubscribers(UnroutedTopic) {
+			e.notifyDrop(topic, m.payload)
+			return nil
 		}
+		routed := &message{
+			headers:    m.headers,
+			ctx:        m.ctx,
+			publishCtx: m.publishCtx,
+			payload:    UnroutedEvent{Topic: topic, Payload: m.payload},
+		}
+		return e.publish(routed, UnroutedTopic, sync)
+	}
+	return nil
+}
+
+// notifyPublishError notifies the observer of a message published by the eventbus itself, like the
+// delayed messages and the payloads of the periodic jobs, which could not be published. The errors reported with OnDrop() by the publication
+// itself are ignored.
+func (e *EventBus) notifyPublishError(topic string, payload any, err error) {
+	if err == nil || e.opts.observer == nil {
+		return
+	}
+	switch err {
+	case ErrNoSubscriber, ErrQueueFull, ErrRateLimited:
+		return
+	}
+	e.opts.observer.OnDrop(topic, payload, err)
+}
+
+// notifyDrop notifies the observer of a message dropped because the topic has no handlers.
+func (e *EventBus) notifyDrop(topic string, payload any) {
+	if e.opts.observer != nil {
+		e.opts.observer.OnDrop(topic, payload, ErrNoSubscriber)
 	}
+}
+
+// PublishEnvelope publishes a message with custom metadata to the topic of the envelope asynchronously.
+// The ID, Time and Source of the envelope are set by the eventbus if they are empty.
+func (e *EventBus) PublishEnvelope(env AnyEnvelope) error {
+	m := env.toMessage()
+	return e.publish(m, m.topic, false)
+}
 
-	return ch.(*channel).publishSync(payload)
+// PublishEnvelopeSync is the synchronous version of PublishEnvelope.
+func (e *EventBus) PublishEnvelopeSync(env AnyEnvelope) error {
+	m := env.toMessage()
+	return e.publish(m, m.topic, true)
 }
 
-// Close closes the eventbus
+// Close closes the eventbus and waits for the goroutines dispatching
+// messages to the handlers of every topic to exit.
+// Close must not be called from within a handler, as it would wait for itself.
 func (e *EventBus) Close() {
-	e.once.Do(func() {
-		e.channels.Range(func(key any, ch any) bool {
-			ch.(*channel).close()
-			return true
-		})
+	e.CloseTimeout(0)
+}
+
+// CloseTimeout closes the eventbus like Close, but waits at most timeout
+// for the running handlers, the runs of the periodic jobs and the publication of the due delayed messages to return.
+// A timeout less than or equal to zero means waiting forever.
+// If some handlers are still running when the timeout expires, it returns
+// a *CloseTimeoutError listing their topics.
+func (e *EventBus) CloseTimeout(timeout time.Duration) error {
+	start := time.Now()
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = start.Add(timeout)
+	}
+	topics := e.stopJobs(deadline)
+	topics = append(topics, e.closeDelayed(deadline)...)
+	e.Lock()
+	e.closed = true
+	e.Unlock()
+	e.stopReaper()
+
+	var channels []*channel
+	e.channels.Range(func(key any, ch any) bool {
+		if deadline.IsZero() {
+			ch.(*channel).stop()
+		} else if !ch.(*channel).tryStop() {
+			// A publisher blocked by a running handler holds the lock of the channel until the handler returns.
+			go ch.(*channel).stop()
+		}
+		channels = append(channels, ch.(*channel))
+		return true
 	})
+	e.retired.Range(func(ch any, _ any) bool {
+		channels = append(channels, ch.(*channel))
+		return true
+	})
+
+	if !deadline.IsZero() && time.Until(deadline) < closeGrace {
+		// The deadline has been consumed by the jobs or the delayed messages, the idle channels still get a chance to exit.
+		deadline = time.Now().Add(closeGrace)
+	}
+	for _, ch := range channels {
+		if deadline.IsZero() {
+			ch.wait(0)
+			continue
+		}
+		// Once the deadline has passed, only check whether the loop has already exited.
+		remaining := time.Until(deadline)
+		if remaining > 0 && ch.wait(remaining) {
+			continue
+		}
+		if !ch.exited() {
+			topics = append(topics, ch.topic)
+		}
+	}
+
+	var err error
+	if len(topics) > 0 {
+		sort.Strings(topics)
+		err = &CloseTimeoutError{Topics: slices.Compact(topics)}
+	}
+	e.logClose(time.Since(start), err)
+	return err
+}
+
+// waitUntil waits for wg, at most until the deadline if it is not zero. It reports whether wg is done.
+func waitUntil(wg *sync.WaitGroup, deadline time.Time) bool {
+	if deadline.IsZero() {
+		wg.Wait()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
 }
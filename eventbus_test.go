@@ -1,7 +1,9 @@
 package eventbus
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,7 +17,7 @@ func busHandlerTwo(topic string, val int) {
 }
 
 func Test_newChannel(t *testing.T) {
-	ch := newChannel("test_topic", -1)
+	ch := newChannel("test_topic", -1, nil)
 	assert.NotNil(t, ch)
 	assert.NotNil(t, ch.channel)
 	assert.Equal(t, "test_topic", ch.topic)
@@ -23,7 +25,7 @@ func Test_newChannel(t *testing.T) {
 	assert.NotNil(t, ch.handlers)
 	ch.close()
 
-	bufferedCh := newChannel("test_topic", 100)
+	bufferedCh := newChannel("test_topic", 100, nil)
 	assert.NotNil(t, bufferedCh)
 	assert.NotNil(t, bufferedCh.channel)
 	assert.Equal(t, 100, cap(bufferedCh.channel))
@@ -32,7 +34,7 @@ func Test_newChannel(t *testing.T) {
 	assert.NotNil(t, bufferedCh.handlers)
 	bufferedCh.close()
 
-	bufferedZeroCh := newChannel("test_topic", 0)
+	bufferedZeroCh := newChannel("test_topic", 0, nil)
 	assert.NotNil(t, bufferedZeroCh)
 	assert.NotNil(t, bufferedZeroCh.channel)
 	assert.Equal(t, "test_topic", bufferedZeroCh.topic)
@@ -42,7 +44,7 @@ func Test_newChannel(t *testing.T) {
 }
 
 func Test_channelSubscribe(t *testing.T) {
-	ch := newChannel("test_topic", -1)
+	ch := newChannel("test_topic", -1, nil)
 	assert.NotNil(t, ch)
 	assert.NotNil(t, ch.channel)
 	assert.Equal(t, "test_topic", ch.topic)
@@ -55,7 +57,7 @@ func Test_channelSubscribe(t *testing.T) {
 }
 
 func Test_channelUnsubscribe(t *testing.T) {
-	ch := newChannel("test_topic", -1)
+	ch := newChannel("test_topic", -1, nil)
 	assert.NotNil(t, ch)
 	assert.NotNil(t, ch.channel)
 	assert.Equal(t, "test_topic", ch.topic)
@@ -73,7 +75,7 @@ func Test_channelUnsubscribe(t *testing.T) {
 }
 
 func Test_channelClose(t *testing.T) {
-	ch := newChannel("test_topic", -1)
+	ch := newChannel("test_topic", -1, nil)
 	assert.NotNil(t, ch)
 	assert.NotNil(t, ch.channel)
 	assert.Equal(t, "test_topic", ch.topic)
@@ -86,7 +88,7 @@ func Test_channelClose(t *testing.T) {
 }
 
 func Test_channelPublish(t *testing.T) {
-	ch := newChannel("test_topic", -1)
+	ch := newChannel("test_topic", -1, nil)
 	assert.NotNil(t, ch)
 	assert.NotNil(t, ch.channel)
 	assert.Equal(t, "test_topic", ch.topic)
@@ -113,7 +115,7 @@ func Test_channelPublish(t *testing.T) {
 }
 
 func Test_channelPublishSync(t *testing.T) {
-	ch := newChannel("test_topic", -1)
+	ch := newChannel("test_topic", -1, nil)
 	assert.NotNil(t, ch)
 	assert.NotNil(t, ch.channel)
 	assert.Equal(t, "test_topic", ch.topic)
@@ -257,6 +259,54 @@ func Test_EventBusPublishSync(t *testing.T) {
 	bus.Close()
 }
 
+func Test_EventBusCloseWaitsHandlers(t *testing.T) {
+	bus := NewBuffered(10)
+	var finished atomic.Bool
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+	})
+	assert.Nil(t, err)
+
+	err = bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	bus.Close()
+	assert.True(t, finished.Load())
+
+	ch, _ := bus.channels.Load("testtopic")
+	assert.True(t, ch.(*channel).exited())
+
+	err = bus.Publish("newtopic", 1)
+	assert.Equal(t, ErrChannelClosed, err)
+	bus.Close()
+}
+
+func Test_EventBusCloseTimeout(t *testing.T) {
+	bus := NewBuffered(10)
+	release := make(chan struct{})
+	err := bus.Subscribe("slowtopic", func(topic string, val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+	err = bus.Subscribe("fasttopic", busHandlerOne)
+	assert.Nil(t, err)
+
+	err = bus.Publish("slowtopic", 1)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+
+	err = bus.CloseTimeout(10 * time.Millisecond)
+	assert.True(t, errors.Is(err, ErrCloseTimeout))
+	var timeoutErr *CloseTimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.Equal(t, []string{"slowtopic"}, timeoutErr.Topics)
+
+	close(release)
+	err = bus.CloseTimeout(time.Second)
+	assert.Nil(t, err)
+}
+
 func BenchmarkEventBusPublish(b *testing.B) {
 	bus := New()
 	bus.Subscribe("testtopic", busHandlerOne)
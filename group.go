@@ -0,0 +1,280 @@
+package eventbus
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// GroupBalancing defines how the messages of a topic are spread among the members of a consumer group.
+type GroupBalancing int
+
+const (
+	// RoundRobin delivers the messages to the members of a group in turn, skipping the members
+	// still handling a message. It is the default balancing.
+	RoundRobin GroupBalancing = iota
+	// LeastBusy delivers each message to the member of a group running the fewest handler calls,
+	// taking turns between the members equally busy.
+	LeastBusy
+)
+
+// WithGroupBalancing sets how the messages are spread among the members of the consumer groups.
+// The default balancing is RoundRobin.
+func WithGroupBalancing(balancing GroupBalancing) Option {
+	return func(o *options) {
+		o.groupBalancing = balancing
+	}
+}
+
+// group is a consumer group of a topic, whose members compete for the messages.
+type group struct {
+	mu      sync.Mutex
+	members []*groupMember
+	// next is the index of the member whose turn it is.
+	next int
+	// ready hands a message to any member of the group done with its previous message.
+	ready chan groupDelivery
+}
+
+// groupMember is a handler of a consumer group. Each member runs its own goroutine,
+// so that the members of a group handle the messages concurrently.
+type groupMember struct {
+	h *handler
+	// busy is the number of calls of the handler in progress.
+	busy atomic.Int32
+	// deliveries hands the messages to the goroutine of the member.
+	deliveries chan groupDelivery
+	// stopCh is closed when the member is removed from its group.
+	stopCh chan struct{}
+}
+
+// groupDelivery is a message handed to the goroutine of a member of a consumer group.
+type groupDelivery struct {
+	ctx     context.Context
+	m       *message
+	attempt int
+}
+
+// SubscribeGroup subscribes a handler to a topic as a member of a consumer group.
+// Each message of the topic is delivered to exactly one member of each group, according to the
+// GroupBalancing set with WithGroupBalancing(), while the handlers subscribed with Subscribe()
+// keep receiving every message. The members of a group are typically a pool of workers sharing the load.
+// Each member handles the messages from its own goroutine, so that the members of a group run
+// concurrently, a member waiting for its current message to be handled before receiving the next one.
+// If all the members of a group are busy, the message goes to the first member done with its message.
+// The messages published with PublishSync() are delivered to the member from the calling goroutine.
+// The handler must follow the signatures supported by Subscribe(). Unlike Subscribe(), subscribing
+// the same handler several times to a group adds as many members, so that a pool of workers may be
+// started from a single function. The members of a group do not receive the retained message
+// nor the history of the topic.
+func (e *EventBus) SubscribeGroup(topic string, group string, handler any) error {
+	h, err := newHandler(reflect.ValueOf(handler))
+	if err != nil {
+		return err
+	}
+	h.group = group
+
+	return e.withChannel(topic, func(ch *channel) error {
+		return ch.subscribeGroup(h)
+	})
+}
+
+// UnsubscribeGroup removes a handler from a consumer group of a topic.
+// If the handler has been subscribed several times to the group, a single member is removed.
+func (e *EventBus) UnsubscribeGroup(topic string, group string, handler any) error {
+	ch, ok := e.channels.Load(topic)
+	if !ok {
+		return ErrNoSubscriber
+	}
+	err := ch.(*channel).unsubscribeGroup(group, handler)
+	if err == ErrChannelClosed && !e.isClosed() {
+		// The topic has been removed concurrently.
+		return ErrNoSubscriber
+	}
+	if err == nil && e.opts.autoRemove {
+		e.removeIfUnused(ch.(*channel), 0)
+	}
+	return err
+}
+
+// subscribeGroup adds a handler to a consumer group of the channel.
+func (c *channel) subscribeGroup(h *handler) error {
+	c.RLock()
+	defer c.RUnlock()
+	if c.closed {
+		return ErrChannelClosed
+	}
+	c.touch()
+	if c.durable != nil {
+		defer c.durable.signal()
+	}
+	member := &groupMember{
+		h:          h,
+		deliveries: make(chan groupDelivery),
+		stopCh:     make(chan struct{}),
+	}
+	g, _ := c.groups.LoadOrStore(h.group, &group{ready: make(chan groupDelivery)})
+	g.(*group).add(member)
+
+	// The channel is not closed, so its loop is running and the count can't drop to zero meanwhile.
+	c.running.Add(1)
+	go c.runGroupMember(g.(*group), member)
+	return nil
+}
+
+// runGroupMember calls the handler of a member of a consumer group with the messages handed to it,
+// until the member is removed or the channel is stopped.
+func (c *channel) runGroupMember(g *group, member *groupMember) {
+	defer c.exit()
+	for {
+		var d groupDelivery
+		select {
+		case d = <-member.deliveries:
+		case d = <-g.ready:
+		case <-member.stopCh:
+			return
+		case <-c.stopCh:
+			return
+		}
+		member.busy.Add(1)
+		c.callAttempt(d.ctx, member.h, d.m, d.attempt)
+		member.busy.Add(-1)
+	}
+}
+
+// unsubscribeGroup removes a handler from a consumer group of the channel.
+func (c *channel) unsubscribeGroup(name string, handler any) error {
+	c.RLock()
+	defer c.RUnlock()
+	if c.closed {
+		return ErrChannelClosed
+	}
+	c.touch()
+	g, ok := c.groups.Load(name)
+	if !ok || !g.(*group).remove(reflect.ValueOf(handler).Pointer()) {
+		return ErrNoSubscriber
+	}
+	return nil
+}
+
+// deliverGroups delivers a message to one member of each consumer group of the channel,
+// from the calling goroutine if sync is true, or from the goroutine of the member otherwise.
+// It reports whether the message has been delivered to at least one handler.
+func (c *channel) deliverGroups(ctx context.Context, m *message, sync bool) bool {
+	delivered := false
+	c.groups.Range(func(key any, g any) bool {
+		if c.callGroup(ctx, g.(*group), m, 1, sync) {
+			delivered = true
+		}
+		return true
+	})
+	return delivered
+}
+
+// callGroup delivers the message to a member of a consumer group. If sync is true, the handler of the
+// member is called from the calling goroutine, otherwise the message is handed to the goroutine of a member,
+// the first idle member in the order of the balancing, or the first member done with its message if they are all busy.
+// It reports false if the group has no members.
+func (c *channel) callGroup(ctx context.Context, g *group, m *message, attempt int, sync bool) bool {
+	for {
+		members := g.pick(c.opts.groupBalancing)
+		if members == nil {
+			return false
+		}
+		if sync {
+			member := members[0]
+			member.busy.Add(1)
+			defer member.busy.Add(-1)
+			c.callAttempt(ctx, member.h, m, attempt)
+			return true
+		}
+
+		d := groupDelivery{ctx: ctx, m: m, attempt: attempt}
+		for _, member := range members {
+			select {
+			case member.deliveries <- d:
+				return true
+			default:
+			}
+		}
+		select {
+		case g.ready <- d:
+			return true
+		case <-members[0].stopCh:
+			// The member has been removed meanwhile, the message goes to another member.
+		case <-c.stopCh:
+			c.drop(m.payload, ErrChannelClosed)
+			return true
+		}
+	}
+}
+
+// groupMembers returns the number of handlers subscribed to the consumer groups of the channel.
+func (c *channel) groupMembers() int {
+	n := 0
+	c.groups.Range(func(key any, g any) bool {
+		n += g.(*group).len()
+		return true
+	})
+	return n
+}
+
+// add adds a member to the group.
+func (g *group) add(member *groupMember) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.members = append(g.members, member)
+}
+
+// remove removes the handler whose function is at the given address from the group,
+// and stops the goroutine of the member. It reports whether the handler was a member of the group.
+func (g *group) remove(fn uintptr) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// The last member subscribed is removed first.
+	for i := len(g.members) - 1; i >= 0; i-- {
+		member := g.members[i]
+		if member.h.fn.Pointer() == fn {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			close(member.stopCh)
+			return true
+		}
+	}
+	return false
+}
+
+// len returns the number of members of the group.
+func (g *group) len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.members)
+}
+
+// pick returns the members of the group in the order they should receive a message, the member whose
+// turn it is first, or the least busy ones first for LeastBusy. Returns nil if the group is empty.
+func (g *group) pick(balancing GroupBalancing) []*groupMember {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := len(g.members)
+	if n == 0 {
+		return nil
+	}
+	start := g.next % n
+	g.next = start + 1
+	members := make([]*groupMember, 0, n)
+	members = append(members, g.members[start:]...)
+	members = append(members, g.members[:start]...)
+	if balancing != LeastBusy {
+		return members
+	}
+	busy := make(map[*groupMember]int32, n)
+	for _, member := range members {
+		busy[member] = member.busy.Load()
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return busy[members[i]] < busy[members[j]]
+	})
+	return members
+}
@@ -0,0 +1,210 @@
+package eventbus
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SubscribeGroup(t *testing.T) {
+	bus := New()
+	var workers [3]atomic.Int32
+	var broadcast, auditors atomic.Int32
+	err := bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) { workers[0].Add(1) })
+	assert.Nil(t, err)
+	err = bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) { workers[1].Add(1) })
+	assert.Nil(t, err)
+	err = bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) { workers[2].Add(1) })
+	assert.Nil(t, err)
+	err = bus.SubscribeGroup("testtopic", "auditors", func(topic string, val int) { auditors.Add(1) })
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", func(topic string, val int) { broadcast.Add(1) })
+	assert.Nil(t, err)
+	err = bus.SubscribeGroup("testtopic", "workers", 1)
+	assert.Equal(t, ErrHandlerIsNotFunc, err)
+	assert.Equal(t, 5, bus.SubscriberCount("testtopic"))
+
+	for i := 0; i < 9; i++ {
+		assert.Nil(t, bus.PublishSync("testtopic", i))
+	}
+	for i := range workers {
+		assert.Equal(t, int32(3), workers[i].Load())
+	}
+	assert.Equal(t, int32(9), auditors.Load())
+	assert.Equal(t, int32(9), broadcast.Load())
+	bus.Close()
+}
+
+func Test_SubscribeGroupSameHandler(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	var received atomic.Int32
+	handler := func(topic string, val int) { received.Add(1) }
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, bus.SubscribeGroup("testtopic", "workers", handler))
+	}
+	assert.Equal(t, 3, bus.SubscriberCount("testtopic"))
+
+	assert.Nil(t, bus.PublishSync("testtopic", 1))
+	assert.Equal(t, int32(1), received.Load())
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, bus.UnsubscribeGroup("testtopic", "workers", handler))
+	}
+	assert.Equal(t, ErrNoSubscriber, bus.UnsubscribeGroup("testtopic", "workers", handler))
+	assert.Equal(t, ErrNoSubscriber, bus.UnsubscribeGroup("testtopic", "others", handler))
+	assert.Equal(t, ErrNoSubscriber, bus.UnsubscribeGroup("notopic", "workers", handler))
+	assert.False(t, bus.HasSubscribers("testtopic"))
+
+	assert.Nil(t, bus.PublishSync("testtopic", 2))
+	assert.Equal(t, int32(1), received.Load())
+	bus.Close()
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrNoSubscriber.Error())
+}
+
+func Test_SubscribeGroupLeastBusy(t *testing.T) {
+	bus := NewWithOptions(WithWorkers(2), WithBufferSize(10), WithGroupBalancing(LeastBusy))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var slow, fast atomic.Int32
+	err := bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) {
+		slow.Add(1)
+		close(started)
+		<-release
+	})
+	assert.Nil(t, err)
+	err = bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) {
+		fast.Add(1)
+	})
+	assert.Nil(t, err)
+
+	// The idle members take the messages in turn, until the slow member gets one.
+	published := int32(0)
+	for slow.Load() == 0 {
+		assert.Nil(t, bus.Publish("testtopic", 0))
+		published++
+		time.Sleep(time.Millisecond)
+	}
+	<-started
+	assert.Eventually(t, func() bool { return fast.Load()+slow.Load() == published }, time.Second, time.Millisecond)
+	received := fast.Load()
+	for i := 1; i <= 5; i++ {
+		assert.Nil(t, bus.Publish("testtopic", i))
+	}
+	assert.Eventually(t, func() bool { return fast.Load() == received+5 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), slow.Load())
+	close(release)
+	bus.Close()
+}
+
+func Test_SubscribeGroupParallel(t *testing.T) {
+	for _, balancing := range []GroupBalancing{RoundRobin, LeastBusy} {
+		bus := NewWithOptions(WithWorkers(1), WithBufferSize(10), WithGroupBalancing(balancing))
+		// Each member waits for the others, which only returns if the members run concurrently.
+		var running atomic.Int32
+		var once sync.Once
+		all := make(chan struct{})
+		var parallel atomic.Int32
+		handler := func(topic string, val int) {
+			if running.Add(1) == 4 {
+				once.Do(func() { close(all) })
+			}
+			select {
+			case <-all:
+				parallel.Add(1)
+			case <-time.After(time.Second):
+			}
+		}
+		for i := 0; i < 4; i++ {
+			assert.Nil(t, bus.SubscribeGroup("testtopic", "workers", handler))
+		}
+
+		for i := 0; i < 4; i++ {
+			assert.Nil(t, bus.Publish("testtopic", i))
+		}
+		assert.Eventually(t, func() bool { return parallel.Load() == 4 }, 500*time.Millisecond, time.Millisecond)
+		bus.Close()
+	}
+}
+
+func Test_SubscribeGroupSlowMember(t *testing.T) {
+	bus := NewBuffered(100)
+	release := make(chan struct{})
+	var slow, fast, broadcast atomic.Int32
+	err := bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) {
+		slow.Add(1)
+		<-release
+	})
+	assert.Nil(t, err)
+	err = bus.SubscribeGroup("testtopic", "workers", func(topic string, val int) {
+		fast.Add(1)
+	})
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", func(topic string, val int) {
+		broadcast.Add(1)
+	})
+	assert.Nil(t, err)
+
+	// A blocked member neither holds up the other members nor the other handlers.
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, bus.Publish("testtopic", i))
+	}
+	assert.Eventually(t, func() bool {
+		return broadcast.Load() == 10 && slow.Load()+fast.Load() == 10
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), slow.Load())
+	close(release)
+	bus.Close()
+}
+
+func Test_SubscribeGroupConcurrent(t *testing.T) {
+	bus := NewWithOptions(WithWorkers(4), WithBufferSize(100))
+	var mu sync.Mutex
+	received := make(map[int]int)
+	handler := func(topic string, val int) {
+		mu.Lock()
+		received[val]++
+		mu.Unlock()
+	}
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, bus.SubscribeGroup("testtopic", "workers", handler))
+	}
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, bus.Publish("testtopic", i))
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 100
+	}, time.Second, time.Millisecond)
+	bus.Close()
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, 1, received[i])
+	}
+}
+
+func Test_SubscribeGroupDelivery(t *testing.T) {
+	bus := NewWithOptions(WithVisibilityTimeout(10 * time.Millisecond))
+	var mu sync.Mutex
+	var attempts []int
+	err := bus.SubscribeGroup("testtopic", "workers", func(d *Delivery[int]) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, d.Attempt)
+		if d.Attempt == 2 {
+			d.Ack()
+		}
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, bus.PublishSync("testtopic", 1))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) == 2
+	}, time.Second, time.Millisecond)
+	bus.Close()
+	assert.Equal(t, []int{1, 2}, attempts)
+}
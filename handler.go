@@ -0,0 +1,135 @@
+package eventbus
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+)
+
+var (
+	// envelopeType is the type of the interface implemented by every *Envelope[T].
+	envelopeType = reflect.TypeOf((*AnyEnvelope)(nil)).Elem()
+	// contextType is the type of context.Context.
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// handler is a function subscribed to a topic.
+type handler struct {
+	fn reflect.Value
+	// withContext is true if the first parameter of the function is a context.Context.
+	withContext bool
+	// envelope is true if the function receives an *Envelope[T] instead of a topic and a payload.
+	envelope bool
+	// delivery is true if the function receives a *Delivery[T] to acknowledge explicitly.
+	delivery bool
+	// group is the consumer group of the handler, empty for the handlers receiving every message.
+	group string
+	// pacer delivers the messages to a debounced or throttled handler, nil otherwise.
+	pacer *pacer
+	// batcher accumulates the messages for a handler subscribed with SubscribeBatch(), nil otherwise.
+	batcher *batcher
+	// name is the name of the function, as reported by the runtime.
+	name string
+}
+
+// newHandler checks the signature of a function subscribed to a topic and wraps it.
+// The supported signatures are, with an optional leading context.Context parameter:
+//
+//	func(topic string, payload T)
+//	func(env *Envelope[T])
+//	func(d *Delivery[T])
+//
+// The function may return values, in which case a last return value
+// implementing error is reported as the result of the handler.
+func newHandler(fn reflect.Value) (*handler, error) {
+	if !fn.IsValid() || fn.Kind() != reflect.Func {
+		return nil, ErrHandlerIsNotFunc
+	}
+
+	h := &handler{fn: fn}
+	typ := fn.Type()
+	in := make([]reflect.Type, typ.NumIn())
+	for i := range in {
+		in[i] = typ.In(i)
+	}
+	if len(in) > 0 && in[0] == contextType {
+		h.withContext = true
+		in = in[1:]
+	}
+
+	switch len(in) {
+	case 1:
+		switch {
+		case in[0].Kind() != reflect.Pointer:
+			return nil, ErrHandlerParamNum
+		case in[0].Implements(deliveryType):
+			h.delivery = true
+		case in[0].Implements(envelopeType):
+			h.envelope = true
+		default:
+			return nil, ErrHandlerParamNum
+		}
+	case 2:
+		if in[0].Kind() != reflect.String {
+			return nil, ErrHandlerFirstParam
+		}
+	default:
+		return nil, ErrHandlerParamNum
+	}
+
+	h.name = funcName(fn)
+	return h, nil
+}
+
+// funcName returns the name of a function, as reported by the runtime.
+func funcName(fn reflect.Value) string {
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// args returns the arguments to call the handler with for the given message.
+// ctx is passed to the handlers accepting a context, and a to the handlers receiving a *Delivery[T].
+func (h *handler) args(ctx context.Context, topic reflect.Value, m *message, a *ack) []reflect.Value {
+	typ := h.fn.Type()
+	args := make([]reflect.Value, 0, typ.NumIn())
+	if h.withContext {
+		args = append(args, reflect.ValueOf(&ctx).Elem())
+	}
+
+	if h.delivery {
+		d := reflect.New(typ.In(len(args)).Elem())
+		d.Interface().(anyDelivery).fromAck(a)
+		return append(args, d)
+	}
+
+	if h.envelope {
+		// Every handler receives its own envelope, so that it can't alter what other handlers receive.
+		env := reflect.New(typ.In(len(args)).Elem())
+		env.Interface().(AnyEnvelope).fromMessage(m)
+		return append(args, env)
+	}
+
+	var payloadValue reflect.Value
+	if m.payload == nil {
+		// If the parameter passed to the handler is nil,
+		// it initializes a new payload element based on the
+		// type of the second parameter of the handler using the reflect package.
+		payloadValue = reflect.New(typ.In(len(args) + 1)).Elem()
+	} else {
+		payloadValue = reflect.ValueOf(m.payload)
+	}
+	return append(args, topic, payloadValue)
+}
+
+// call calls the handler with the given message,
+// and returns the error returned by the handler, if any.
+func (h *handler) call(ctx context.Context, topic reflect.Value, m *message, a *ack) error {
+	out := h.fn.Call(h.args(ctx, topic, m, a))
+	if n := len(out); n > 0 {
+		err, _ := out[n-1].Interface().(error)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newHandlerSignatures(t *testing.T) {
+	h, err := newHandler(reflect.ValueOf(busHandlerOne))
+	assert.Nil(t, err)
+	assert.False(t, h.envelope)
+	assert.False(t, h.withContext)
+
+	h, err = newHandler(reflect.ValueOf(func(env *Envelope[int]) error { return nil }))
+	assert.Nil(t, err)
+	assert.True(t, h.envelope)
+	assert.False(t, h.withContext)
+
+	h, err = newHandler(reflect.ValueOf(func(ctx context.Context, topic string, val int) {}))
+	assert.Nil(t, err)
+	assert.False(t, h.envelope)
+	assert.True(t, h.withContext)
+
+	h, err = newHandler(reflect.ValueOf(func(ctx context.Context, env *Envelope[int]) {}))
+	assert.Nil(t, err)
+	assert.True(t, h.envelope)
+	assert.True(t, h.withContext)
+
+	_, err = newHandler(reflect.ValueOf(1))
+	assert.Equal(t, ErrHandlerIsNotFunc, err)
+	_, err = newHandler(reflect.Value{})
+	assert.Equal(t, ErrHandlerIsNotFunc, err)
+	_, err = newHandler(reflect.ValueOf(func(env Envelope[int]) {}))
+	assert.Equal(t, ErrHandlerParamNum, err)
+	_, err = newHandler(reflect.ValueOf(func(topic string) {}))
+	assert.Equal(t, ErrHandlerParamNum, err)
+	_, err = newHandler(reflect.ValueOf(func(ctx context.Context) {}))
+	assert.Equal(t, ErrHandlerParamNum, err)
+	_, err = newHandler(reflect.ValueOf(func(topic int, val int) {}))
+	assert.Equal(t, ErrHandlerFirstParam, err)
+}
+
+func Test_newHandler(t *testing.T) {
+	h, err := newHandler(reflect.ValueOf(busHandlerOne))
+	assert.Nil(t, err)
+	assert.Equal(t, "github.com/werbenhu/eventbus.busHandlerOne", h.name)
+
+	_, err = newHandler(reflect.ValueOf(func() {}))
+	assert.Equal(t, ErrHandlerParamNum, err)
+}
+
+func Test_handlerCall(t *testing.T) {
+	topic := reflect.ValueOf("testtopic")
+	m := &message{id: "id", topic: "testtopic", payload: 1}
+
+	var received int
+	h, _ := newHandler(reflect.ValueOf(func(topic string, val int) {
+		received = val
+	}))
+	assert.Nil(t, h.call(context.Background(), topic, m, nil))
+	assert.Equal(t, 1, received)
+
+	h, _ = newHandler(reflect.ValueOf(func(topic string, val int) error {
+		return errors.New("failed")
+	}))
+	assert.EqualError(t, h.call(context.Background(), topic, m, nil), "failed")
+
+	var env *Envelope[int]
+	h, _ = newHandler(reflect.ValueOf(func(e *Envelope[int]) {
+		env = e
+	}))
+	assert.Nil(t, h.call(context.Background(), topic, m, nil))
+	assert.Equal(t, &Envelope[int]{ID: "id", Topic: "testtopic", Payload: 1}, env)
+
+	h, _ = newHandler(reflect.ValueOf(func(e *Envelope[int]) {
+		env = e
+	}))
+	assert.Nil(t, h.call(context.Background(), topic, &message{topic: "testtopic"}, nil))
+	assert.Equal(t, 0, env.Payload)
+}
+
+func Test_handlerCallWithContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	topic := reflect.ValueOf("testtopic")
+	m := &message{topic: "testtopic", payload: 1}
+
+	var values []any
+	h, _ := newHandler(reflect.ValueOf(func(ctx context.Context, topic string, val int) {
+		values = append(values, ctx.Value(key{}), val)
+	}))
+	assert.Nil(t, h.call(ctx, topic, m, nil))
+	h, _ = newHandler(reflect.ValueOf(func(ctx context.Context, env *Envelope[int]) {
+		values = append(values, ctx.Value(key{}), env.Payload)
+	}))
+	assert.Nil(t, h.call(ctx, topic, m, nil))
+	h, _ = newHandler(reflect.ValueOf(func(ctx context.Context, topic string, val int) {
+		values = append(values, val)
+	}))
+	assert.Nil(t, h.call(ctx, topic, &message{topic: "testtopic"}, nil))
+	assert.Equal(t, []any{"value", 1, "value", 1, 0}, values)
+}
@@ -0,0 +1,128 @@
+package eventbus
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// minHistoryCapacity is the initial capacity of the ring buffer of a history.
+const minHistoryCapacity = 16
+
+// History makes a topic keep its last messages, so that SubscribeFrom() can replay them to new handlers.
+// The history keeps at most size messages, and drops the messages older than maxAge.
+// Zero means no limit, but at least one of the limits must be set for the history to be enabled.
+// A topic with a history is stateful, see TopicOption. RemoveTopic() discards the history.
+func History(size int, maxAge time.Duration) TopicOption {
+	return func(o *topicOptions) {
+		o.historySize = size
+		o.historyAge = maxAge
+	}
+}
+
+// SubscribeFrom subscribes to a topic like Subscribe, and replays to the handler the messages
+// of the history of the topic whose sequence number is greater than or equal to seq,
+// before delivering the live messages, without gaps or duplicates.
+// The sequence numbers start at 1, so zero replays the whole history. If some of the requested
+// messages have already left the history, the replay starts from the oldest message of the history:
+// handlers receiving an *Envelope can detect the gap with Envelope.Seq.
+// The history of a durable topic is its write-ahead log.
+// Returns ErrNoHistory if the topic is not configured with History() or Durable().
+func (e *EventBus) SubscribeFrom(topic string, seq uint64, handler any) error {
+	if _, err := newHandler(reflect.ValueOf(handler)); err != nil {
+		return err
+	}
+	if t := e.opts.topicOptions(topic); !t.hasHistory() && t.durable == nil {
+		return ErrNoHistory
+	}
+
+	return e.withChannel(topic, func(ch *channel) error {
+		return ch.subscribeFrom(seq, handler)
+	})
+}
+
+// historyEntry is a message kept in a history.
+type historyEntry struct {
+	m *message
+	// at is the time the message was added to the history.
+	at time.Time
+}
+
+// history is a ring buffer of the last messages dispatched to a topic, in order of sequence number.
+// It is not safe for concurrent use.
+type history struct {
+	size   int
+	maxAge time.Duration
+	buf    []historyEntry
+	// head is the index of the oldest entry, and n the number of entries.
+	head int
+	n    int
+}
+
+// newHistory returns an empty history keeping at most size messages no older than maxAge.
+func newHistory(size int, maxAge time.Duration) *history {
+	return &history{size: size, maxAge: maxAge}
+}
+
+// add adds a message to the history, dropping the oldest messages if the history is full.
+func (h *history) add(m *message, now time.Time) {
+	h.expire(now)
+	entry := historyEntry{m: m, at: now}
+	if h.size > 0 && h.n == h.size {
+		h.buf[h.head] = entry
+		h.head = (h.head + 1) % len(h.buf)
+		return
+	}
+	if h.n == len(h.buf) {
+		h.grow()
+	}
+	h.buf[(h.head+h.n)%len(h.buf)] = entry
+	h.n++
+}
+
+// grow increases the capacity of the ring buffer, up to the size of the history.
+func (h *history) grow() {
+	capacity := 2 * len(h.buf)
+	if capacity < minHistoryCapacity {
+		capacity = minHistoryCapacity
+	}
+	if h.size > 0 && capacity > h.size {
+		capacity = h.size
+	}
+	buf := make([]historyEntry, capacity)
+	for i := 0; i < h.n; i++ {
+		buf[i] = h.entry(i)
+	}
+	h.buf = buf
+	h.head = 0
+}
+
+// expire drops the messages older than the maximum age of the history.
+func (h *history) expire(now time.Time) {
+	if h.maxAge <= 0 {
+		return
+	}
+	for h.n > 0 && now.Sub(h.buf[h.head].at) > h.maxAge {
+		h.buf[h.head] = historyEntry{}
+		h.head = (h.head + 1) % len(h.buf)
+		h.n--
+	}
+}
+
+// entry returns the i-th oldest entry of the history.
+func (h *history) entry(i int) historyEntry {
+	return h.buf[(h.head+i)%len(h.buf)]
+}
+
+// since returns the messages of the history whose sequence number is greater than or equal to seq.
+func (h *history) since(seq uint64, now time.Time) []*message {
+	h.expire(now)
+	start := sort.Search(h.n, func(i int) bool {
+		return h.entry(i).m.seq >= seq
+	})
+	messages := make([]*message, 0, h.n-start)
+	for i := start; i < h.n; i++ {
+		messages = append(messages, h.entry(i).m)
+	}
+	return messages
+}
@@ -0,0 +1,142 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// historySeqs returns the sequence numbers of the messages.
+func historySeqs(messages []*message) []uint64 {
+	seqs := make([]uint64, 0, len(messages))
+	for _, m := range messages {
+		seqs = append(seqs, m.seq)
+	}
+	return seqs
+}
+
+func Test_HistorySize(t *testing.T) {
+	h := newHistory(20, 0)
+	now := time.Now()
+	for i := 1; i <= 50; i++ {
+		h.add(&message{seq: uint64(i)}, now)
+	}
+	assert.Equal(t, 20, len(h.buf))
+	seqs := historySeqs(h.since(0, now))
+	assert.Equal(t, 20, len(seqs))
+	assert.Equal(t, uint64(31), seqs[0])
+	assert.Equal(t, uint64(50), seqs[19])
+	assert.Equal(t, []uint64{48, 49, 50}, historySeqs(h.since(48, now)))
+	assert.Empty(t, h.since(51, now))
+}
+
+func Test_HistoryAge(t *testing.T) {
+	h := newHistory(0, time.Hour)
+	now := time.Now()
+	for i := 1; i <= 100; i++ {
+		h.add(&message{seq: uint64(i)}, now.Add(time.Duration(i)*time.Second))
+	}
+	// The history is not bounded by size.
+	assert.Equal(t, 100, len(h.since(0, now.Add(100*time.Second))))
+
+	seqs := historySeqs(h.since(0, now.Add(time.Hour+50*time.Second)))
+	assert.Equal(t, 51, len(seqs))
+	assert.Equal(t, uint64(50), seqs[0])
+	assert.Empty(t, h.since(0, now.Add(2*time.Hour)))
+
+	h.add(&message{seq: 101}, now.Add(2*time.Hour))
+	assert.Equal(t, []uint64{101}, historySeqs(h.since(0, now.Add(2*time.Hour))))
+}
+
+func Test_SubscribeFrom(t *testing.T) {
+	bus := NewWithOptions(WithTopic("testtopic", History(10, 0)))
+	err := bus.SubscribeFrom("othertopic", 0, busHandlerOne)
+	assert.Equal(t, ErrNoHistory, err)
+	err = bus.SubscribeFrom("testtopic", 0, 1)
+	assert.Equal(t, ErrHandlerIsNotFunc, err)
+
+	for i := 1; i <= 15; i++ {
+		err = bus.PublishSync("testtopic", i)
+		assert.Nil(t, err)
+	}
+
+	var all []uint64
+	err = bus.SubscribeFrom("testtopic", 0, func(env *Envelope[int]) {
+		assert.Equal(t, int(env.Seq), env.Payload)
+		all = append(all, env.Seq)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, all)
+
+	var recent []int
+	err = bus.SubscribeFrom("testtopic", 14, func(topic string, val int) {
+		recent = append(recent, val)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{14, 15}, recent)
+
+	err = bus.PublishSync("testtopic", 16)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(16), all[len(all)-1])
+	assert.Equal(t, []int{14, 15, 16}, recent)
+	bus.Close()
+}
+
+func Test_SubscribeFromLive(t *testing.T) {
+	bus := NewWithOptions(WithTopic("testtopic", History(1000, 0)), WithBufferSize(100))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 500; i++ {
+			err := bus.Publish("testtopic", i)
+			assert.Nil(t, err)
+		}
+	}()
+
+	var mu sync.Mutex
+	var received []uint64
+	time.Sleep(time.Millisecond)
+	err := bus.SubscribeFrom("testtopic", 1, func(env *Envelope[int]) {
+		mu.Lock()
+		received = append(received, env.Seq)
+		mu.Unlock()
+	})
+	assert.Nil(t, err)
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 500
+	}, time.Second, time.Millisecond)
+	bus.Close()
+
+	// The history and the live messages are delivered without gaps or duplicates.
+	for i, seq := range received {
+		assert.Equal(t, uint64(i+1), seq)
+	}
+}
+
+func Test_HistoryTopicLifecycle(t *testing.T) {
+	bus := NewWithOptions(
+		WithTopic("testtopic", History(10, 0)),
+		WithNoSubscriberPolicy(NoSubscriberError),
+		WithTopicIdleTTL(20*time.Millisecond),
+	)
+	err := bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	time.Sleep(60 * time.Millisecond)
+
+	var received []int
+	err = bus.SubscribeFrom("testtopic", 0, func(topic string, val int) {
+		received = append(received, val)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1}, received)
+	bus.Close()
+
+	err = bus.SubscribeFrom("testtopic", 0, busHandlerOne)
+	assert.Equal(t, ErrChannelClosed, err)
+}
@@ -0,0 +1,112 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// defaultSlowHandlerThreshold is the default duration above which a handler call is logged as slow.
+const defaultSlowHandlerThreshold = time.Second
+
+// WithLogger logs the activity of the eventbus with structured attributes: topic creation and closing,
+// dropped messages, handler errors, panics and slow handlers, and the closing of the eventbus.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithSlowHandlerThreshold sets the duration above which a handler call is logged as slow
+// by the logger set with WithLogger(). Zero disables the logging of slow handlers.
+// The default threshold is one second.
+func WithSlowHandlerThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = &threshold
+	}
+}
+
+// logObserver is an Observer logging the activity of the eventbus.
+type logObserver struct {
+	NopObserver
+	logger        *slog.Logger
+	slowThreshold time.Duration
+}
+
+// newLogObserver returns the observer logging the activity of the eventbus according to the options.
+func newLogObserver(o *options) *logObserver {
+	threshold := defaultSlowHandlerThreshold
+	if o.slowThreshold != nil {
+		threshold = *o.slowThreshold
+	}
+	return &logObserver{logger: o.logger, slowThreshold: threshold}
+}
+
+// OnTopicCreated implements Observer.
+func (l *logObserver) OnTopicCreated(topic string) {
+	l.logger.Debug("eventbus: topic created", slog.String("topic", topic))
+}
+
+// OnTopicClosed implements Observer.
+func (l *logObserver) OnTopicClosed(topic string) {
+	l.logger.Debug("eventbus: topic closed", slog.String("topic", topic))
+}
+
+// OnDeliverEnd implements Observer.
+func (l *logObserver) OnDeliverEnd(topic string, handler string, payload any, duration time.Duration, err error) {
+	var panicErr *PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		l.logger.Error("eventbus: handler panicked",
+			slog.String("topic", topic),
+			slog.String("handler", handler),
+			slog.Duration("duration", duration),
+			slog.Any("panic", panicErr.Value),
+			slog.String("stack", string(panicErr.Stack)),
+		)
+	case err != nil:
+		l.logger.Error("eventbus: handler failed",
+			slog.String("topic", topic),
+			slog.String("handler", handler),
+			slog.Duration("duration", duration),
+			slog.Any("error", err),
+		)
+	case l.slowThreshold > 0 && duration > l.slowThreshold:
+		l.logger.Warn("eventbus: slow handler",
+			slog.String("topic", topic),
+			slog.String("handler", handler),
+			slog.Duration("duration", duration),
+			slog.Duration("threshold", l.slowThreshold),
+		)
+	}
+}
+
+// OnDrop implements Observer.
+// Messages published to topics without handlers are logged at the debug level,
+// since publishing without subscribers is usually expected.
+func (l *logObserver) OnDrop(topic string, payload any, reason error) {
+	level := slog.LevelWarn
+	if reason == ErrNoSubscriber {
+		level = slog.LevelDebug
+	}
+	l.logger.Log(context.Background(), level, "eventbus: message dropped",
+		slog.String("topic", topic),
+		slog.Any("reason", reason),
+	)
+}
+
+// logClose logs the closing of the eventbus.
+func (e *EventBus) logClose(duration time.Duration, err error) {
+	if e.opts.logger == nil {
+		return
+	}
+	if err != nil {
+		e.opts.logger.Warn("eventbus: closed with handlers still running",
+			slog.Duration("duration", duration),
+			slog.Any("error", err),
+		)
+		return
+	}
+	e.opts.logger.Info("eventbus: closed", slog.Duration("duration", duration))
+}
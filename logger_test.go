@@ -0,0 +1,95 @@
+package eventbus
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func Test_newLogObserver(t *testing.T) {
+	o := &options{logger: slog.Default()}
+	assert.Equal(t, defaultSlowHandlerThreshold, newLogObserver(o).slowThreshold)
+
+	WithSlowHandlerThreshold(0)(o)
+	assert.Equal(t, time.Duration(0), newLogObserver(o).slowThreshold)
+}
+
+func Test_EventBusLogger(t *testing.T) {
+	var buf bytes.Buffer
+	bus := NewWithOptions(WithLogger(newTestLogger(&buf)), WithSlowHandlerThreshold(time.Millisecond))
+
+	err := bus.Subscribe("testtopic", func(topic string, val int) error {
+		switch val {
+		case 1:
+			return errors.New("failed")
+		case 2:
+			time.Sleep(2 * time.Millisecond)
+		case 3:
+			panic("boom")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 0)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 2)
+	assert.Nil(t, err)
+	assert.Panics(t, func() {
+		bus.PublishSync("testtopic", 3)
+	})
+	err = bus.PublishSync("droptopic", 1)
+	assert.Nil(t, err)
+	bus.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	find := func(msg string) string {
+		for _, line := range lines {
+			if strings.Contains(line, `msg="`+msg+`"`) {
+				return line
+			}
+		}
+		return ""
+	}
+
+	assert.Contains(t, find("eventbus: topic created"), "level=DEBUG")
+	assert.Contains(t, find("eventbus: topic created"), "topic=testtopic")
+	assert.Contains(t, find("eventbus: handler failed"), "level=ERROR")
+	assert.Contains(t, find("eventbus: handler failed"), "error=failed")
+	assert.Contains(t, find("eventbus: handler failed"), "handler=github.com/werbenhu/eventbus.Test_EventBusLogger.func1")
+	assert.Contains(t, find("eventbus: slow handler"), "level=WARN")
+	assert.Contains(t, find("eventbus: slow handler"), "threshold=1ms")
+	assert.Contains(t, find("eventbus: handler panicked"), "panic=boom")
+	assert.Contains(t, find("eventbus: message dropped"), "topic=droptopic")
+	assert.Contains(t, find("eventbus: message dropped"), "level=DEBUG")
+	assert.Contains(t, find("eventbus: topic closed"), "level=DEBUG")
+	assert.Contains(t, find("eventbus: closed"), "level=INFO")
+}
+
+func Test_EventBusLoggerCloseTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	bus := NewWithOptions(WithLogger(newTestLogger(&buf)))
+	release := make(chan struct{})
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+	err = bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+
+	err = bus.CloseTimeout(time.Millisecond)
+	assert.NotNil(t, err)
+	close(release)
+	assert.Contains(t, buf.String(), `level=WARN msg="eventbus: closed with handlers still running"`)
+}
@@ -0,0 +1,174 @@
+package eventbus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the latency histograms.
+var latencyBuckets = [...]time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a snapshot of a latency histogram.
+type Histogram struct {
+	// Bounds are the inclusive upper bounds of the buckets, in increasing order.
+	Bounds []time.Duration
+	// Counts are the number of observations in each bucket. It has one more element
+	// than Bounds, counting the observations greater than the last bound.
+	Counts []uint64
+	// Count is the total number of observations.
+	Count uint64
+	// Sum is the sum of all observations.
+	Sum time.Duration
+}
+
+// Mean returns the average of the observations, or zero if there is none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Stats is a snapshot of the metrics of a topic or a pipe.
+type Stats struct {
+	// Published is the number of messages accepted by Publish() and PublishSync().
+	Published uint64
+	// Delivered is the number of handler calls that returned without error.
+	Delivered uint64
+	// Dropped is the number of messages discarded without being delivered to any handler.
+	Dropped uint64
+	// Failed is the number of handler calls that returned a non-nil error.
+	Failed uint64
+	// Panicked is the number of handler calls that panicked.
+	Panicked uint64
+	// QueueDepth is the number of messages waiting to be delivered.
+	QueueDepth int
+	// HandlerLatency is the histogram of the execution time of the handlers.
+	HandlerLatency Histogram
+	// QueueWait is the histogram of the time spent by asynchronous messages in the queue.
+	QueueWait Histogram
+}
+
+// histogram is a lock-free latency histogram using the latencyBuckets.
+type histogram struct {
+	counts [len(latencyBuckets) + 1]atomic.Uint64
+	sum    atomic.Int64
+}
+
+// observe records a duration in the histogram.
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// snapshot returns the current state of the histogram.
+// Count is the sum of the loaded Counts, so that it is consistent with them under concurrent observations.
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Bounds: append([]time.Duration(nil), latencyBuckets[:]...),
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+		s.Count += s.Counts[i]
+	}
+	return s
+}
+
+// metrics holds the counters of a topic or a pipe.
+// All its fields are updated atomically, so it's cheap enough to be always enabled.
+type metrics struct {
+	published      atomic.Uint64
+	delivered      atomic.Uint64
+	dropped        atomic.Uint64
+	failed         atomic.Uint64
+	panicked       atomic.Uint64
+	handlerLatency histogram
+	queueWait      histogram
+}
+
+// publish counts a published message. m may be nil if the metrics are disabled.
+func (m *metrics) publish() {
+	if m != nil {
+		m.published.Add(1)
+	}
+}
+
+// drop counts n dropped messages. m may be nil if the metrics are disabled.
+func (m *metrics) drop(n int) {
+	if m != nil {
+		m.dropped.Add(uint64(n))
+	}
+}
+
+// deliver records the result and the duration of a handler call.
+// m may be nil if the metrics are disabled.
+func (m *metrics) deliver(duration time.Duration, panicked bool, err error) {
+	if m == nil {
+		return
+	}
+	switch {
+	case panicked:
+		m.panicked.Add(1)
+	case err != nil:
+		m.failed.Add(1)
+	default:
+		m.delivered.Add(1)
+	}
+	m.handlerLatency.observe(duration)
+}
+
+// wait records the time spent by a message in the queue. m may be nil if the metrics are disabled.
+func (m *metrics) wait(duration time.Duration) {
+	if m != nil {
+		m.queueWait.observe(duration)
+	}
+}
+
+// snapshot returns the current state of the metrics.
+// Only the queue depth is reported if m is nil.
+func (m *metrics) snapshot(queueDepth int) Stats {
+	if m == nil {
+		return Stats{QueueDepth: queueDepth}
+	}
+	return Stats{
+		Published:      m.published.Load(),
+		Delivered:      m.delivered.Load(),
+		Dropped:        m.dropped.Load(),
+		Failed:         m.failed.Load(),
+		Panicked:       m.panicked.Load(),
+		QueueDepth:     queueDepth,
+		HandlerLatency: m.handlerLatency.snapshot(),
+		QueueWait:      m.queueWait.snapshot(),
+	}
+}
+
+// Stats returns a snapshot of the metrics of every topic, indexed by topic.
+func (e *EventBus) Stats() map[string]Stats {
+	stats := make(map[string]Stats)
+	e.channels.Range(func(key any, ch any) bool {
+		c := ch.(*channel)
+		stats[c.topic] = c.metrics.snapshot(c.queueDepth())
+		return true
+	})
+	return stats
+}
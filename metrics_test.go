@@ -0,0 +1,155 @@
+package eventbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_histogram(t *testing.T) {
+	var h histogram
+	h.observe(5 * time.Microsecond)
+	h.observe(10 * time.Microsecond)
+	h.observe(2 * time.Millisecond)
+	h.observe(time.Minute)
+
+	s := h.snapshot()
+	assert.Equal(t, len(latencyBuckets), len(s.Bounds))
+	assert.Equal(t, len(latencyBuckets)+1, len(s.Counts))
+	assert.Equal(t, uint64(2), s.Counts[0])
+	assert.Equal(t, uint64(1), s.Counts[5])
+	assert.Equal(t, uint64(1), s.Counts[len(latencyBuckets)])
+	assert.Equal(t, uint64(4), s.Count)
+	assert.Equal(t, time.Minute+2*time.Millisecond+15*time.Microsecond, s.Sum)
+	assert.Equal(t, s.Sum/4, s.Mean())
+	assert.Equal(t, time.Duration(0), Histogram{}.Mean())
+}
+
+func Test_histogramConcurrentSnapshot(t *testing.T) {
+	var h histogram
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100000; i++ {
+			h.observe(time.Duration(i%1000) * time.Microsecond)
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		s := h.snapshot()
+		var total uint64
+		for _, count := range s.Counts {
+			total += count
+		}
+		assert.Equal(t, total, s.Count)
+	}
+}
+
+func Test_EventBusStats(t *testing.T) {
+	bus := New()
+	err := bus.PublishSync("droptopic", 1)
+	assert.Nil(t, err)
+
+	err = bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", func(topic string, val int) error {
+		if val%2 == 0 {
+			return errors.New("even")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	err = bus.Subscribe("panictopic", func(topic string, val int) {
+		panic("panic")
+	})
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = bus.Publish("testtopic", i)
+		assert.Nil(t, err)
+	}
+	err = bus.PublishSync("testtopic", 3)
+	assert.Nil(t, err)
+	assert.Panics(t, func() {
+		bus.PublishSync("panictopic", 1)
+	})
+
+	assert.Eventually(t, func() bool {
+		return bus.Stats()["testtopic"].HandlerLatency.Count == 8
+	}, time.Second, time.Millisecond)
+
+	stats := bus.Stats()
+	assert.Equal(t, 3, len(stats))
+	assert.Equal(t, uint64(1), stats["droptopic"].Published)
+	assert.Equal(t, uint64(1), stats["droptopic"].Dropped)
+	assert.Equal(t, uint64(1), stats["panictopic"].Panicked)
+	assert.Equal(t, uint64(0), stats["panictopic"].Delivered)
+
+	s := stats["testtopic"]
+	assert.Equal(t, uint64(4), s.Published)
+	assert.Equal(t, uint64(0), s.Dropped)
+	assert.Equal(t, uint64(6), s.Delivered)
+	assert.Equal(t, uint64(2), s.Failed)
+	assert.Equal(t, uint64(0), s.Panicked)
+	assert.Equal(t, 0, s.QueueDepth)
+	assert.Equal(t, uint64(3), s.QueueWait.Count)
+	bus.Close()
+}
+
+func Test_EventBusStatsDroppedOnClose(t *testing.T) {
+	bus := NewBuffered(10)
+	release := make(chan struct{})
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+	for i := 0; i < 4; i++ {
+		err = bus.Publish("testtopic", i)
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		return bus.Stats()["testtopic"].QueueDepth == 3
+	}, time.Second, time.Millisecond)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	bus.Close()
+	s := bus.Stats()["testtopic"]
+	assert.Equal(t, uint64(3), s.Dropped)
+	assert.Equal(t, uint64(1), s.Delivered)
+}
+
+func Test_PipeStats(t *testing.T) {
+	p := NewBufferedPipe[int](10)
+	err := p.PublishSync(1)
+	assert.Nil(t, err)
+
+	err = p.Subscribe(pipeHandlerOne)
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		err = p.Publish(i)
+		assert.Nil(t, err)
+	}
+	err = p.PublishSync(3)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		return p.Stats().Delivered == 4
+	}, time.Second, time.Millisecond)
+
+	s := p.Stats()
+	assert.Equal(t, uint64(5), s.Published)
+	assert.Equal(t, uint64(1), s.Dropped)
+	assert.Equal(t, uint64(0), s.Panicked)
+	assert.Equal(t, uint64(4), s.HandlerLatency.Count)
+	assert.Equal(t, uint64(3), s.QueueWait.Count)
+	p.Close()
+}
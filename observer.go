@@ -0,0 +1,111 @@
+package eventbus
+
+import "time"
+
+// Observer receives notifications about the activity of an EventBus,
+// to feed custom metrics, tracing or logging backends.
+// Observers are called synchronously from the goroutine doing the work,
+// so they must be fast and must not call the eventbus back.
+// Embed NopObserver to implement only some of the methods.
+type Observer interface {
+	// OnTopicCreated is called when a topic is created.
+	OnTopicCreated(topic string)
+	// OnTopicClosed is called when a topic is closed, either by Close() or by removing the topic.
+	OnTopicClosed(topic string)
+	// OnPublish is called when a message is accepted by Publish() or PublishSync().
+	OnPublish(topic string, payload any)
+	// OnDeliverStart is called before a handler is called with a message.
+	// handler is the name of the handler function.
+	OnDeliverStart(topic string, handler string, payload any)
+	// OnDeliverEnd is called after a handler returns. err is the error returned by the handler,
+	// or a *PanicError if the handler panicked.
+	OnDeliverEnd(topic string, handler string, payload any, duration time.Duration, err error)
+	// OnDrop is called when a message is discarded without being delivered to any handler.
+	// reason is one of:
+	//   - ErrNoSubscriber if the topic has no handlers,
+	//   - ErrChannelClosed if the topic was closed before the message could be delivered,
+	//   - ErrQueueFull if the queue of the topic is full, see WithBackpressure(),
+	//   - ErrDuplicate if the message has already been published, see Dedup(),
+	//   - ErrRateLimited if the message exceeds the rate limit of the topic, see RateLimit(),
+	//   - ErrNacked if a handler rejected the message with Delivery.Nack() and it is not redelivered,
+	//   - ErrPayloadType if the payload does not match the type of a batched or durable topic,
+	//   - the error of the write-ahead log if a message can't be written to or read from a durable topic.
+	// payload is nil if the message could not be read from the write-ahead log.
+	OnDrop(topic string, payload any, reason error)
+}
+
+// NopObserver is an Observer doing nothing.
+type NopObserver struct{}
+
+// OnTopicCreated implements Observer.
+func (NopObserver) OnTopicCreated(topic string) {}
+
+// OnTopicClosed implements Observer.
+func (NopObserver) OnTopicClosed(topic string) {}
+
+// OnPublish implements Observer.
+func (NopObserver) OnPublish(topic string, payload any) {}
+
+// OnDeliverStart implements Observer.
+func (NopObserver) OnDeliverStart(topic string, handler string, payload any) {}
+
+// OnDeliverEnd implements Observer.
+func (NopObserver) OnDeliverEnd(topic string, handler string, payload any, duration time.Duration, err error) {
+}
+
+// OnDrop implements Observer.
+func (NopObserver) OnDrop(topic string, payload any, reason error) {}
+
+// observers notifies several observers in order.
+type observers []Observer
+
+func (o observers) OnTopicCreated(topic string) {
+	for _, observer := range o {
+		observer.OnTopicCreated(topic)
+	}
+}
+
+func (o observers) OnTopicClosed(topic string) {
+	for _, observer := range o {
+		observer.OnTopicClosed(topic)
+	}
+}
+
+func (o observers) OnPublish(topic string, payload any) {
+	for _, observer := range o {
+		observer.OnPublish(topic, payload)
+	}
+}
+
+func (o observers) OnDeliverStart(topic string, handler string, payload any) {
+	for _, observer := range o {
+		observer.OnDeliverStart(topic, handler, payload)
+	}
+}
+
+func (o observers) OnDeliverEnd(topic string, handler string, payload any, duration time.Duration, err error) {
+	for _, observer := range o {
+		observer.OnDeliverEnd(topic, handler, payload, duration, err)
+	}
+}
+
+func (o observers) OnDrop(topic string, payload any, reason error) {
+	for _, observer := range o {
+		observer.OnDrop(topic, payload, reason)
+	}
+}
+
+// WithObserver registers an observer notified of the activity of the eventbus.
+// It can be used several times to register several observers.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		switch current := o.observer.(type) {
+		case nil:
+			o.observer = observer
+		case observers:
+			o.observer = append(current, observer)
+		default:
+			o.observer = observers{current, observer}
+		}
+	}
+}
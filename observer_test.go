@@ -0,0 +1,167 @@
+package eventbus
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingObserver records the notifications it receives.
+type recordingObserver struct {
+	NopObserver
+	sync.Mutex
+	events []string
+	errs   []error
+}
+
+func (o *recordingObserver) record(event string) {
+	o.Lock()
+	defer o.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) OnTopicCreated(topic string) {
+	o.record("created:" + topic)
+}
+
+func (o *recordingObserver) OnTopicClosed(topic string) {
+	o.record("closed:" + topic)
+}
+
+func (o *recordingObserver) OnPublish(topic string, payload any) {
+	o.record("publish:" + topic)
+}
+
+func (o *recordingObserver) OnDeliverStart(topic string, handler string, payload any) {
+	o.record("start:" + topic)
+}
+
+func (o *recordingObserver) OnDeliverEnd(topic string, handler string, payload any, duration time.Duration, err error) {
+	o.record("end:" + topic)
+	o.Lock()
+	defer o.Unlock()
+	o.errs = append(o.errs, err)
+}
+
+func (o *recordingObserver) OnDrop(topic string, payload any, reason error) {
+	o.record("drop:" + topic + ":" + reason.Error())
+}
+
+func (o *recordingObserver) Events() []string {
+	o.Lock()
+	defer o.Unlock()
+	return append([]string(nil), o.events...)
+}
+
+func Test_EventBusObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+
+	err := bus.PublishSync("droptopic", 1)
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", func(topic string, val int) error {
+		if val == 2 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 2)
+	assert.Nil(t, err)
+	bus.Close()
+
+	events := observer.Events()
+	assert.Equal(t, []string{
+		"created:droptopic",
+		"publish:droptopic",
+		"drop:droptopic:" + ErrNoSubscriber.Error(),
+		"created:testtopic",
+		"publish:testtopic",
+		"start:testtopic",
+		"end:testtopic",
+		"publish:testtopic",
+		"start:testtopic",
+		"end:testtopic",
+	}, events[:10])
+	assert.ElementsMatch(t, []string{"closed:droptopic", "closed:testtopic"}, events[10:])
+	assert.Nil(t, observer.errs[0])
+	assert.EqualError(t, observer.errs[1], "failed")
+}
+
+func Test_EventBusObserverPanic(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		panic("boom")
+	})
+	assert.Nil(t, err)
+	assert.PanicsWithValue(t, "boom", func() {
+		bus.PublishSync("testtopic", 1)
+	})
+
+	var panicErr *PanicError
+	assert.True(t, errors.As(observer.errs[0], &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Equal(t, "handler panicked: boom", panicErr.Error())
+	bus.Close()
+}
+
+func Test_EventBusMultipleObservers(t *testing.T) {
+	first := &recordingObserver{}
+	second := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(first), WithObserver(NopObserver{}), WithObserver(second))
+	assert.Equal(t, 3, len(bus.opts.observer.(observers)))
+
+	err := bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	bus.Close()
+	assert.Contains(t, first.Events(), "drop:testtopic:"+ErrNoSubscriber.Error())
+	assert.Equal(t, first.Events(), second.Events())
+}
+
+func Test_EventBusObserverDropOnClose(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	bus.bufferSize = 10
+	release := make(chan struct{})
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		err = bus.Publish("testtopic", i)
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		return bus.QueueDepth("testtopic") == 2
+	}, time.Second, time.Millisecond)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	bus.Close()
+
+	dropped := 0
+	for _, event := range observer.Events() {
+		if event == "drop:testtopic:"+ErrChannelClosed.Error() {
+			dropped++
+		}
+	}
+	assert.Equal(t, 2, dropped)
+}
+
+func Test_EventBusObserverNoSubscriberPolicy(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer), WithNoSubscriberPolicy(NoSubscriberDrop))
+	err := bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"drop:testtopic:" + ErrNoSubscriber.Error()}, observer.Events())
+	bus.Close()
+}
@@ -0,0 +1,163 @@
+package eventbus
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures an EventBus created by NewWithOptions, or a Pipe created by NewPipeWithOptions.
+// The options specific to topics are ignored by pipes.
+type Option func(*options)
+
+// options holds the settings of an EventBus or a Pipe.
+type options struct {
+	autoRemove   bool
+	idleTTL      time.Duration
+	noSubscriber NoSubscriberPolicy
+	observer     Observer
+	source       string
+	propagator   Propagator
+	contextKeys  []any
+	logger       *slog.Logger
+	// slowThreshold is nil if WithSlowHandlerThreshold() is not used.
+	slowThreshold *time.Duration
+	bufferSize    int
+	workers       int
+	backpressure  BackpressurePolicy
+	panicHandler  func(topic string, err *PanicError)
+	noMetrics     bool
+	topics        map[string]*topicOptions
+	codec         Codec
+	dataDir       string
+	segmentSize   int64
+	fsync         bool
+	// redeliveryDelay is the delay before redelivering a message to a durable subscriber.
+	redeliveryDelay time.Duration
+	// visibilityTimeout is the time a handler has to settle a *Delivery[T].
+	visibilityTimeout time.Duration
+	groupBalancing    GroupBalancing
+	flushDelayed      bool
+}
+
+// NoSubscriberPolicy defines what happens to a message published to a topic without handlers.
+type NoSubscriberPolicy int
+
+const (
+	// NoSubscriberAccept creates the topic if necessary and accepts the message, which is then discarded.
+	// This is the default policy.
+	NoSubscriberAccept NoSubscriberPolicy = iota
+	// NoSubscriberDrop silently drops the message without creating the topic.
+	NoSubscriberDrop
+	// NoSubscriberError drops the message without creating the topic, and returns ErrNoSubscriber.
+	NoSubscriberError
+	// NoSubscriberRoute publishes an UnroutedEvent wrapping the message to UnroutedTopic.
+	// The message is dropped if UnroutedTopic has no handlers either.
+	NoSubscriberRoute
+)
+
+// UnroutedTopic is the topic receiving the messages published to topics
+// without handlers, when the eventbus uses the NoSubscriberRoute policy.
+const UnroutedTopic = "$unrouted"
+
+// UnroutedEvent is the payload of the messages published to UnroutedTopic.
+// Handlers of UnroutedTopic must have the signature func(topic string, event UnroutedEvent).
+type UnroutedEvent struct {
+	// Topic is the topic the message was originally published to.
+	Topic string
+	// Payload is the payload of the original message.
+	Payload any
+}
+
+// WithAutoRemoveTopics removes a topic, along with its channel and goroutine,
+// as soon as its last handler unsubscribes.
+func WithAutoRemoveTopics() Option {
+	return func(o *options) {
+		o.autoRemove = true
+	}
+}
+
+// WithTopicIdleTTL removes the topics that have no handlers and have not been
+// published to or subscribed to for at least ttl.
+func WithTopicIdleTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.idleTTL = ttl
+	}
+}
+
+// WithNoSubscriberPolicy sets what happens to messages published to topics without handlers.
+func WithNoSubscriberPolicy(policy NoSubscriberPolicy) Option {
+	return func(o *options) {
+		o.noSubscriber = policy
+	}
+}
+
+// WithSource sets the Source of the messages published without an explicit source,
+// typically the name of the service or component owning the eventbus.
+func WithSource(source string) Option {
+	return func(o *options) {
+		o.source = source
+	}
+}
+
+// WithBufferSize sets the buffer size of the channel of every topic, or of a pipe.
+// A size less than or equal to zero means unbuffered channels, which is the default.
+func WithBufferSize(size int) Option {
+	return func(o *options) {
+		o.bufferSize = size
+	}
+}
+
+// WithWorkers sets the number of goroutines dispatching the messages of every topic, or of a pipe.
+// The default is one goroutine, which delivers the messages in the order they were published.
+// With more workers, the messages are delivered concurrently and their order is not preserved.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithPanicHandler recovers the panics of the handlers instead of crashing the process,
+// and calls fn with the topic of the handler and the recovered panic.
+// The topic is empty for the handlers of a pipe.
+// fn is called from the goroutine of the panicking handler and must not block.
+func WithPanicHandler(fn func(topic string, err *PanicError)) Option {
+	return func(o *options) {
+		o.panicHandler = fn
+	}
+}
+
+// WithMetrics enables or disables the metrics returned by Stats().
+// The metrics are enabled by default. When disabled, the Stats only report the queue depth.
+func WithMetrics(enabled bool) Option {
+	return func(o *options) {
+		o.noMetrics = !enabled
+	}
+}
+
+// newOptions returns the options set by opts.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger != nil {
+		WithObserver(newLogObserver(&o))(&o)
+	}
+	return o
+}
+
+// workerCount returns the number of goroutines dispatching the messages of a topic or a pipe.
+func (o *options) workerCount() int {
+	if o.workers <= 0 {
+		return 1
+	}
+	return o.workers
+}
+
+// newMetrics returns the metrics of a topic or a pipe, or nil if the metrics are disabled.
+func (o *options) newMetrics() *metrics {
+	if o.noMetrics {
+		return nil
+	}
+	return &metrics{}
+}
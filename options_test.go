@@ -0,0 +1,188 @@
+package eventbus
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewWithOptions(t *testing.T) {
+	bus := NewWithOptions()
+	assert.NotNil(t, bus)
+	assert.Equal(t, -1, bus.bufferSize)
+	assert.Equal(t, NoSubscriberAccept, bus.opts.noSubscriber)
+	bus.Close()
+}
+
+func Test_NoSubscriberAccept(t *testing.T) {
+	bus := NewWithOptions(WithNoSubscriberPolicy(NoSubscriberAccept))
+	err := bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	_, ok := bus.channels.Load("testtopic")
+	assert.True(t, ok)
+	bus.Close()
+}
+
+func Test_NoSubscriberDrop(t *testing.T) {
+	bus := NewWithOptions(WithNoSubscriberPolicy(NoSubscriberDrop))
+	err := bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	_, ok := bus.channels.Load("testtopic")
+	assert.False(t, ok)
+
+	var received []int
+	err = bus.Subscribe("testtopic", func(topic string, val int) {
+		received = append(received, val)
+	})
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2}, received)
+	bus.Close()
+
+	err = bus.Publish("othertopic", 1)
+	assert.Equal(t, ErrChannelClosed, err)
+}
+
+func Test_NoSubscriberError(t *testing.T) {
+	bus := NewWithOptions(WithNoSubscriberPolicy(NoSubscriberError))
+	err := bus.Publish("testtopic", 1)
+	assert.Equal(t, ErrNoSubscriber, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Equal(t, ErrNoSubscriber, err)
+	_, ok := bus.channels.Load("testtopic")
+	assert.False(t, ok)
+
+	err = bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.Unsubscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Equal(t, ErrNoSubscriber, err)
+	bus.Close()
+}
+
+func Test_NoSubscriberRoute(t *testing.T) {
+	bus := NewWithOptions(WithNoSubscriberPolicy(NoSubscriberRoute))
+	err := bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	_, ok := bus.channels.Load(UnroutedTopic)
+	assert.False(t, ok)
+
+	var unrouted []UnroutedEvent
+	err = bus.Subscribe(UnroutedTopic, func(topic string, event UnroutedEvent) {
+		assert.Equal(t, UnroutedTopic, topic)
+		unrouted = append(unrouted, event)
+	})
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []UnroutedEvent{{Topic: "testtopic", Payload: 2}}, unrouted)
+	_, ok = bus.channels.Load("testtopic")
+	assert.False(t, ok)
+	bus.Close()
+}
+
+func Test_WithBufferSize(t *testing.T) {
+	bus := NewWithOptions(WithBufferSize(100))
+	assert.Equal(t, 100, bus.bufferSize)
+	err := bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	ch, ok := bus.channels.Load("testtopic")
+	assert.True(t, ok)
+	assert.Equal(t, 100, cap(ch.(*channel).channel))
+	bus.Close()
+
+	bus = NewWithOptions(WithBufferSize(0))
+	assert.Equal(t, -1, bus.bufferSize)
+	bus.Close()
+}
+
+func Test_WithWorkers(t *testing.T) {
+	bus := NewWithOptions(WithBufferSize(10), WithWorkers(4))
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		n := running.Add(1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		wg.Done()
+	})
+	assert.Nil(t, err)
+
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		err = bus.Publish("testtopic", i)
+		assert.Nil(t, err)
+	}
+	wg.Wait()
+	assert.Greater(t, maxRunning.Load(), int32(1))
+
+	ch, _ := bus.channels.Load("testtopic")
+	bus.Close()
+	assert.True(t, ch.(*channel).exited())
+}
+
+func Test_WithPanicHandler(t *testing.T) {
+	var panics []*PanicError
+	var topics []string
+	bus := NewWithOptions(WithPanicHandler(func(topic string, err *PanicError) {
+		topics = append(topics, topic)
+		panics = append(panics, err)
+	}))
+
+	var received []int
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		if val == 1 {
+			panic("boom")
+		}
+		received = append(received, val)
+	})
+	assert.Nil(t, err)
+
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2}, received)
+	assert.Equal(t, []string{"testtopic"}, topics)
+	assert.Equal(t, 1, len(panics))
+	assert.Equal(t, "boom", panics[0].Value)
+	assert.NotEmpty(t, panics[0].Stack)
+
+	stats := bus.Stats()["testtopic"]
+	assert.Equal(t, uint64(1), stats.Panicked)
+	assert.Equal(t, uint64(1), stats.Delivered)
+	bus.Close()
+}
+
+func Test_WithMetrics(t *testing.T) {
+	bus := NewWithOptions(WithMetrics(false))
+	err := bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+
+	ch, _ := bus.channels.Load("testtopic")
+	assert.Nil(t, ch.(*channel).metrics)
+	assert.Equal(t, Stats{}, bus.Stats()["testtopic"])
+	bus.Close()
+
+	bus = NewWithOptions(WithMetrics(true))
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), bus.Stats()["testtopic"].Published)
+	bus.Close()
+}
@@ -0,0 +1,197 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SubscribeOption configures a handler subscribed with Subscribe().
+type SubscribeOption func(*subscribeOptions)
+
+// subscribeOptions holds the settings of a handler.
+type subscribeOptions struct {
+	// debounce is the quiet period of a debounced handler, zero if the handler is not debounced.
+	debounce time.Duration
+	// throttle is the interval of a throttled handler, zero if the handler is not throttled.
+	throttle time.Duration
+	// edges selects the messages delivered to a throttled handler.
+	edges ThrottleEdge
+}
+
+// ThrottleEdge selects the messages delivered to a throttled handler, see WithThrottleEdges().
+type ThrottleEdge int
+
+const (
+	// ThrottleLeading delivers the first message of each interval, as soon as it is published.
+	ThrottleLeading ThrottleEdge = 1 << iota
+	// ThrottleTrailing delivers the last message of each interval, once the interval has elapsed.
+	ThrottleTrailing
+)
+
+// WithDebounce delivers to the handler only the last message of each burst,
+// once no message has been published to the topic for the quiet period d.
+func WithDebounce(d time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.debounce = d
+		o.throttle = 0
+	}
+}
+
+// WithThrottle delivers at most one message per interval to the handler: the first message of
+// an interval is delivered right away and the last one at the end of the interval, the other
+// messages of the interval are skipped. WithThrottleEdges() delivers only one of them.
+func WithThrottle(interval time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.throttle = interval
+		o.debounce = 0
+	}
+}
+
+// WithThrottleEdges selects the messages delivered to a handler throttled with WithThrottle():
+// the first message of each interval (ThrottleLeading), the last one (ThrottleTrailing), or both,
+// which is the default. Subscribe() returns ErrThrottleEdges if edges includes neither of them.
+func WithThrottleEdges(edges ThrottleEdge) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.edges = edges
+	}
+}
+
+// pacer delivers the messages of a topic to a debounced or throttled handler.
+// The messages delivered later are delivered from a timer goroutine,
+// and the calls of the handler are serialized.
+type pacer struct {
+	c    *channel
+	h    *handler
+	opts subscribeOptions
+
+	// callMu serializes the calls of the handler.
+	callMu sync.Mutex
+	mu     sync.Mutex
+	// pending is the message waiting for the end of the quiet period or of the interval, if any.
+	pending    *message
+	pendingCtx context.Context
+	// timer is the debounce timer, and gen identifies its last reset.
+	timer *time.Timer
+	gen   uint64
+	// window is true during a throttling interval.
+	window bool
+}
+
+// newPacer returns the pacer of a handler, or nil if the handler is neither debounced nor throttled.
+// Returns ErrThrottleEdges if the edges of a throttled handler are invalid.
+func (c *channel) newPacer(h *handler, opts []SubscribeOption) (*pacer, error) {
+	o := subscribeOptions{edges: ThrottleLeading | ThrottleTrailing}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.edges&(ThrottleLeading|ThrottleTrailing) == 0 {
+		return nil, ErrThrottleEdges
+	}
+	if o.debounce <= 0 && o.throttle <= 0 {
+		return nil, nil
+	}
+	return &pacer{c: c, h: h, opts: o}, nil
+}
+
+// offer hands a message to the pacer, which delivers it now, later or never.
+func (p *pacer) offer(ctx context.Context, m *message) {
+	if p.opts.debounce > 0 {
+		p.debounce(ctx, m)
+	} else {
+		p.throttle(ctx, m)
+	}
+}
+
+// debounce restarts the quiet period, at the end of which the last message is delivered.
+func (p *pacer) debounce(ctx context.Context, m *message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending, p.pendingCtx = m, ctx
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.gen++
+	gen := p.gen
+	p.timer = time.AfterFunc(p.opts.debounce, func() {
+		p.mu.Lock()
+		if gen != p.gen {
+			// A message has been published meanwhile and restarted the quiet period.
+			p.mu.Unlock()
+			return
+		}
+		m, ctx := p.take()
+		p.mu.Unlock()
+		p.callLater(ctx, m)
+	})
+}
+
+// throttle delivers the message now if no interval is in progress and the leading edge is enabled,
+// or keeps it for the end of the interval if the trailing edge is enabled.
+func (p *pacer) throttle(ctx context.Context, m *message) {
+	p.mu.Lock()
+	if p.window {
+		if p.opts.edges&ThrottleTrailing != 0 {
+			p.pending, p.pendingCtx = m, ctx
+		}
+		p.mu.Unlock()
+		return
+	}
+	p.window = true
+	time.AfterFunc(p.opts.throttle, p.endWindow)
+	if p.opts.edges&ThrottleLeading == 0 {
+		p.pending, p.pendingCtx = m, ctx
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.call(ctx, m)
+}
+
+// endWindow ends a throttling interval, delivering the message kept for the trailing edge if any.
+func (p *pacer) endWindow() {
+	p.mu.Lock()
+	m, ctx := p.take()
+	if m == nil {
+		p.window = false
+		p.mu.Unlock()
+		return
+	}
+	// The trailing delivery starts a new interval, so that the deliveries stay one interval apart.
+	time.AfterFunc(p.opts.throttle, p.endWindow)
+	p.mu.Unlock()
+	p.callLater(ctx, m)
+}
+
+// take removes the pending message. The caller must hold p.mu.
+func (p *pacer) take() (*message, context.Context) {
+	m, ctx := p.pending, p.pendingCtx
+	p.pending, p.pendingCtx = nil, nil
+	return m, ctx
+}
+
+// callLater calls the handler from a timer goroutine, unless the channel has been stopped
+// or the handler has been unsubscribed meanwhile.
+func (p *pacer) callLater(ctx context.Context, m *message) {
+	c := p.c
+	if !c.enter() {
+		c.drop(m.payload, ErrChannelClosed)
+		return
+	}
+	defer c.exit()
+	if c.stopped() {
+		c.drop(m.payload, ErrChannelClosed)
+		return
+	}
+	if h, ok := c.handlers.Load(p.h.fn.Pointer()); !ok || h != p.h {
+		return
+	}
+	p.call(ctx, m)
+}
+
+// call calls the handler, after the previous call has returned.
+func (p *pacer) call(ctx context.Context, m *message) {
+	p.callMu.Lock()
+	defer p.callMu.Unlock()
+	p.c.call(ctx, p.h, m)
+}
@@ -0,0 +1,127 @@
+package eventbus
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewPacer(t *testing.T) {
+	ch := newChannel("testtopic", -1, nil)
+	defer ch.close()
+	h, err := newHandler(reflect.ValueOf(busHandlerOne))
+	assert.Nil(t, err)
+	p, err := ch.newPacer(h, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+
+	p, err = ch.newPacer(h, []SubscribeOption{WithThrottle(time.Second)})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, p.opts.throttle)
+	assert.Equal(t, ThrottleLeading|ThrottleTrailing, p.opts.edges)
+
+	p, err = ch.newPacer(h, []SubscribeOption{WithThrottle(time.Second), WithThrottleEdges(ThrottleLeading), WithDebounce(time.Minute)})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), p.opts.throttle)
+	assert.Equal(t, time.Minute, p.opts.debounce)
+	assert.Equal(t, ThrottleLeading, p.opts.edges)
+
+	_, err = ch.newPacer(h, []SubscribeOption{WithThrottle(time.Second), WithThrottleEdges(0)})
+	assert.Equal(t, ErrThrottleEdges, err)
+}
+
+func Test_SubscribeDebounce(t *testing.T) {
+	bus := New()
+	recorder := &delayedRecorder{}
+	all := &delayedRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle, WithDebounce(30*time.Millisecond))
+	assert.Nil(t, err)
+	// Method values of the same method are the same handler, so a closure is needed.
+	err = bus.Subscribe("testtopic", func(topic string, val int) { all.handle(topic, val) })
+	assert.Nil(t, err)
+
+	for i := 1; i <= 5; i++ {
+		assert.Nil(t, bus.PublishSync("testtopic", i))
+	}
+	assert.Empty(t, recorder.values())
+	assert.Eventually(t, func() bool { return len(recorder.values()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{5}, recorder.values())
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, all.values())
+
+	assert.Nil(t, bus.PublishSync("testtopic", 6))
+	assert.Eventually(t, func() bool { return len(recorder.values()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{5, 6}, recorder.values())
+	bus.Close()
+}
+
+func Test_SubscribeThrottle(t *testing.T) {
+	for _, test := range []struct {
+		edges    ThrottleEdge
+		expected []int
+	}{
+		{ThrottleLeading, []int{1}},
+		{ThrottleTrailing, []int{5}},
+		{ThrottleLeading | ThrottleTrailing, []int{1, 5}},
+	} {
+		bus := New()
+		recorder := &delayedRecorder{}
+		err := bus.Subscribe("testtopic", recorder.handle, WithThrottle(50*time.Millisecond), WithThrottleEdges(test.edges))
+		assert.Nil(t, err)
+
+		for i := 1; i <= 5; i++ {
+			assert.Nil(t, bus.PublishSync("testtopic", i))
+		}
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(t, test.expected, recorder.values())
+		bus.Close()
+	}
+}
+
+func Test_SubscribeThrottleInterval(t *testing.T) {
+	bus := New()
+	var mu sync.Mutex
+	var times []time.Time
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}, WithThrottle(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		assert.Nil(t, bus.PublishSync("testtopic", 1))
+		time.Sleep(time.Millisecond)
+	}
+	bus.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.GreaterOrEqual(t, len(times), 3)
+	for i := 1; i < len(times); i++ {
+		assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), 15*time.Millisecond)
+	}
+}
+
+func Test_SubscribeDebounceUnsubscribe(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer))
+	recorder := &delayedRecorder{}
+	err := bus.Subscribe("testtopic", recorder.handle, WithDebounce(10*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Nil(t, bus.PublishSync("testtopic", 1))
+	assert.Nil(t, bus.Unsubscribe("testtopic", recorder.handle))
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, recorder.values())
+
+	err = bus.Subscribe("testtopic", recorder.handle, WithDebounce(10*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Nil(t, bus.PublishSync("testtopic", 2))
+	bus.Close()
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, recorder.values())
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrChannelClosed.Error())
+}
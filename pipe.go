@@ -2,11 +2,28 @@ package eventbus
 
 import (
 	"reflect"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Handler[T any] func(payload T)
 
+// pipeMessage is a payload travelling through a pipe.
+type pipeMessage[T any] struct {
+	payload T
+	// publishedAt is the time the message was published, used to measure the queue wait.
+	publishedAt time.Time
+}
+
+// pipeHandler is a handler subscribed to a pipe.
+type pipeHandler[T any] struct {
+	fn Handler[T]
+	// name is the name of the function, as reported by the runtime.
+	name string
+}
+
 // Pipe is a wrapper for a channel that allows for asynchronous message passing to subscribers.
 // Use Pipe.Publish() instead of `chan<-` and Pipe.Subscribe() instead of `<-chan`.
 // To pass messages to subscribers synchronously, use Pipe.PublishSync(), which does not use a channel.
@@ -14,23 +31,21 @@ type Handler[T any] func(payload T)
 type Pipe[T any] struct {
 	sync.RWMutex
 	bufferSize int
-	channel    chan T
+	channel    chan pipeMessage[T]
 	handlers   *CowMap
 	closed     bool
 	stopCh     chan struct{}
+	doneCh     chan struct{}
+	// running is the number of loop goroutines that have not exited yet.
+	running  atomic.Int32
+	metrics  *metrics
+	observer Observer
+	opts     options
 }
 
 // NewPipe create a unbuffered pipe
 func NewPipe[T any]() *Pipe[T] {
-	p := &Pipe[T]{
-		bufferSize: -1,
-		channel:    make(chan T),
-		stopCh:     make(chan struct{}),
-		handlers:   NewCowMap(),
-	}
-
-	go p.loop()
-	return p
+	return NewPipeWithOptions[T]()
 }
 
 // NewPipe create a buffered pipe, bufferSize is the buffer size of the pipe
@@ -39,33 +54,135 @@ func NewBufferedPipe[T any](bufferSize int) *Pipe[T] {
 	if bufferSize <= 0 {
 		bufferSize = 1
 	}
+	return NewPipeWithOptions[T](WithBufferSize(bufferSize))
+}
 
+// NewPipeWithOptions creates a pipe configured by the given options.
+// Without options, it is equivalent to NewPipe().
+// The observer set with WithObserver() or WithLogger() is notified with an empty topic.
+func NewPipeWithOptions[T any](opts ...Option) *Pipe[T] {
+	o := newOptions(opts)
 	p := &Pipe[T]{
-		bufferSize: bufferSize,
-		channel:    make(chan T, bufferSize),
+		bufferSize: -1,
 		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
 		handlers:   NewCowMap(),
+		metrics:    o.newMetrics(),
+		observer:   o.observer,
+		opts:       o,
+	}
+	if o.bufferSize > 0 {
+		p.bufferSize = o.bufferSize
+		p.channel = make(chan pipeMessage[T], o.bufferSize)
+	} else {
+		p.channel = make(chan pipeMessage[T])
 	}
 
-	go p.loop()
+	workers := o.workerCount()
+	p.running.Store(int32(workers))
+	for i := 0; i < workers; i++ {
+		go p.loop()
+	}
 	return p
 }
 
 // loop loops forever, receiving published message from the pipe, transfer payload to subscriber by calling handlers
+// doneCh is closed when the last loop goroutine exits.
 func (p *Pipe[T]) loop() {
+	defer func() {
+		if p.running.Add(-1) == 0 {
+			close(p.doneCh)
+		}
+	}()
 	for {
 		select {
-		case payload := <-p.channel:
-			p.handlers.Range(func(key any, fn any) bool {
-				fn.(Handler[T])(payload)
-				return true
-			})
+		case msg, ok := <-p.channel:
+			if !ok {
+				return
+			}
+			if p.stopped() {
+				p.drop(msg.payload, ErrChannelClosed)
+				return
+			}
+			p.metrics.wait(time.Since(msg.publishedAt))
+			p.transfer(msg.payload)
 		case <-p.stopCh:
 			return
 		}
 	}
 }
 
+// stopped reports whether the pipe has been closed.
+// The messages remaining in the buffer of a closed pipe are discarded.
+func (p *Pipe[T]) stopped() bool {
+	select {
+	case <-p.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// transfer calls all the handlers of the pipe with the given payload.
+// The message is dropped if there are no handlers.
+func (p *Pipe[T]) transfer(payload T) {
+	delivered := false
+	p.handlers.Range(func(key any, h any) bool {
+		p.call(h.(*pipeHandler[T]), payload)
+		delivered = true
+		return true
+	})
+	if !delivered {
+		p.drop(payload, ErrNoSubscriber)
+	}
+}
+
+// drop records a message discarded without being delivered to any handler.
+func (p *Pipe[T]) drop(payload T, reason error) {
+	p.metrics.drop(1)
+	if p.observer != nil {
+		p.observer.OnDrop("", payload, reason)
+	}
+}
+
+// dropFull drops a message discarded because the pipe is full.
+func (p *Pipe[T]) dropFull(msg pipeMessage[T]) {
+	p.drop(msg.payload, ErrQueueFull)
+}
+
+// call calls a handler with the given payload and records its metrics.
+// Panics are counted, then passed to the panic handler of the pipe if any,
+// or propagated to the caller otherwise.
+func (p *Pipe[T]) call(h *pipeHandler[T], payload T) {
+	if p.observer != nil {
+		p.observer.OnDeliverStart("", h.name, payload)
+	}
+	start := time.Now()
+	defer func() {
+		r := recover()
+		var err error
+		var panicErr *PanicError
+		if r != nil {
+			panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+			err = panicErr
+		}
+		duration := time.Since(start)
+		p.metrics.deliver(duration, r != nil, err)
+		if p.observer != nil {
+			p.observer.OnDeliverEnd("", h.name, payload, duration, err)
+		}
+		if r == nil {
+			return
+		}
+		if p.opts.panicHandler == nil {
+			panic(r)
+		}
+		p.opts.panicHandler("", panicErr)
+	}()
+
+	h.fn(payload)
+}
+
 // subscribe add a handler to a pipe, return error if the pipe is closed.
 func (p *Pipe[T]) Subscribe(handler Handler[T]) error {
 	p.RLock()
@@ -73,8 +190,8 @@ func (p *Pipe[T]) Subscribe(handler Handler[T]) error {
 	if p.closed {
 		return ErrChannelClosed
 	}
-	key := reflect.ValueOf(handler).Pointer()
-	p.handlers.Store(key, handler)
+	fn := reflect.ValueOf(handler)
+	p.handlers.Store(fn.Pointer(), &pipeHandler[T]{fn: handler, name: funcName(fn)})
 	return nil
 }
 
@@ -91,14 +208,16 @@ func (p *Pipe[T]) Unsubscribe(handler Handler[T]) error {
 }
 
 // Publish triggers the handlers defined for this pipe, transferring the payload to the handlers.
+// If the pipe is full, the payload is handled according to the BackpressurePolicy of the pipe.
 func (p *Pipe[T]) Publish(payload T) error {
 	p.RLock()
 	defer p.RUnlock()
 	if p.closed {
 		return ErrChannelClosed
 	}
-	p.channel <- payload
-	return nil
+	p.published(payload)
+	msg := pipeMessage[T]{payload: payload, publishedAt: time.Now()}
+	return enqueue(p.channel, msg, p.opts.backpressure, p.dropFull)
 }
 
 // PublishSync triggers the handlers defined for this pipe synchronously, without using a channel.
@@ -109,22 +228,68 @@ func (p *Pipe[T]) PublishSync(payload T) error {
 	if p.closed {
 		return ErrChannelClosed
 	}
-	p.handlers.Range(func(key any, fn any) bool {
-		fn.(Handler[T])(payload)
-		return true
-	})
+	p.published(payload)
+	p.transfer(payload)
 	return nil
 }
 
-// close closes the pipe
+// published records a payload accepted by the pipe.
+func (p *Pipe[T]) published(payload T) {
+	p.metrics.publish()
+	if p.observer != nil {
+		p.observer.OnPublish("", payload)
+	}
+}
+
+// SubscriberCount returns the number of handlers subscribed to the pipe.
+func (p *Pipe[T]) SubscriberCount() int {
+	return int(p.handlers.Len())
+}
+
+// Len returns the number of messages published asynchronously to the pipe
+// and waiting to be delivered to its handlers.
+func (p *Pipe[T]) Len() int {
+	return len(p.channel)
+}
+
+// Stats returns a snapshot of the metrics of the pipe.
+func (p *Pipe[T]) Stats() Stats {
+	return p.metrics.snapshot(len(p.channel))
+}
+
+// Close closes the pipe and waits for the goroutines dispatching messages to the handlers to exit.
+// Close must not be called from within a handler, as it would wait for itself.
 func (p *Pipe[T]) Close() {
+	p.CloseTimeout(0)
+}
+
+// CloseTimeout closes the pipe like Close, but waits at most timeout for the running handlers to return.
+// A timeout less than or equal to zero means waiting forever.
+// Returns ErrCloseTimeout if the handlers are still running when the timeout expires.
+func (p *Pipe[T]) CloseTimeout(timeout time.Duration) error {
 	p.Lock()
-	defer p.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.stopCh)
+		close(p.channel)
+		// The messages still in the buffer will never be delivered.
+		// The loop goroutines may receive some of them concurrently, and drop them as well.
+		for msg := range p.channel {
+			p.drop(msg.payload, ErrChannelClosed)
+		}
+	}
+	p.Unlock()
 
-	if p.closed {
-		return
+	if timeout <= 0 {
+		<-p.doneCh
+		return nil
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.doneCh:
+		return nil
+	case <-timer.C:
+		return ErrCloseTimeout
 	}
-	p.closed = true
-	close(p.stopCh)
-	close(p.channel)
 }
@@ -2,6 +2,7 @@ package eventbus
 
 import (
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -130,3 +131,110 @@ func Test_PipeClose(t *testing.T) {
 	assert.Equal(t, ErrChannelClosed, err)
 	p.Close()
 }
+
+func Test_PipeCloseWaitsHandlers(t *testing.T) {
+	p := NewBufferedPipe[int](10)
+	var finished atomic.Bool
+	err := p.Subscribe(func(val int) {
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+	})
+	assert.Nil(t, err)
+
+	err = p.Publish(1)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	p.Close()
+	assert.True(t, finished.Load())
+}
+
+func Test_PipeCloseTimeout(t *testing.T) {
+	p := NewBufferedPipe[int](10)
+	release := make(chan struct{})
+	err := p.Subscribe(func(val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+
+	err = p.Publish(1)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+
+	err = p.CloseTimeout(10 * time.Millisecond)
+	assert.Equal(t, ErrCloseTimeout, err)
+
+	close(release)
+	err = p.CloseTimeout(time.Second)
+	assert.Nil(t, err)
+}
+
+func Test_PipeIntrospection(t *testing.T) {
+	p := NewBufferedPipe[int](10)
+	assert.Equal(t, 0, p.SubscriberCount())
+	assert.Equal(t, 0, p.Len())
+
+	release := make(chan struct{})
+	err := p.Subscribe(func(val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+	err = p.Subscribe(pipeHandlerOne)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, p.SubscriberCount())
+
+	for i := 0; i < 4; i++ {
+		err = p.Publish(i)
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		return p.Len() == 3
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return p.Len() == 0
+	}, time.Second, time.Millisecond)
+	p.Close()
+}
+
+func Test_NewPipeWithOptions(t *testing.T) {
+	p := NewPipeWithOptions[int]()
+	assert.Equal(t, -1, p.bufferSize)
+	assert.Equal(t, 0, cap(p.channel))
+	p.Close()
+
+	var panics []*PanicError
+	p = NewPipeWithOptions[int](
+		WithBufferSize(10),
+		WithWorkers(2),
+		WithBackpressure(BackpressureDropNewest),
+		WithPanicHandler(func(topic string, err *PanicError) {
+			assert.Equal(t, "", topic)
+			panics = append(panics, err)
+		}),
+	)
+	assert.Equal(t, 10, p.bufferSize)
+	assert.Equal(t, 10, cap(p.channel))
+
+	err := p.Subscribe(func(val int) {
+		panic(val)
+	})
+	assert.Nil(t, err)
+	err = p.PublishSync(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(panics))
+	assert.Equal(t, 1, panics[0].Value)
+	assert.Equal(t, uint64(1), p.Stats().Panicked)
+	p.Close()
+	assert.Equal(t, int32(0), p.running.Load())
+}
+
+func Test_PipeWithoutMetrics(t *testing.T) {
+	p := NewPipeWithOptions[int](WithMetrics(false))
+	err := p.Subscribe(pipeHandlerOne)
+	assert.Nil(t, err)
+	err = p.PublishSync(1)
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{}, p.Stats())
+	p.Close()
+}
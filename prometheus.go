@@ -0,0 +1,108 @@
+package eventbus
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsHandler returns an http.Handler rendering the metrics of every topic of the eventbus
+// in the Prometheus text exposition format, without depending on the Prometheus client library.
+//
+//	http.Handle("/metrics", eventbus.MetricsHandler(bus))
+func MetricsHandler(bus *EventBus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		writeMetrics(w, bus.Stats())
+	})
+}
+
+// counterMetric describes a per-topic counter.
+type counterMetric struct {
+	name  string
+	help  string
+	value func(s *Stats) uint64
+}
+
+var counterMetrics = []counterMetric{
+	{"eventbus_published_total", "Number of messages published to the topic.", func(s *Stats) uint64 { return s.Published }},
+	{"eventbus_delivered_total", "Number of handler calls that returned without error.", func(s *Stats) uint64 { return s.Delivered }},
+	{"eventbus_dropped_total", "Number of messages discarded without being delivered to any handler.", func(s *Stats) uint64 { return s.Dropped }},
+	{"eventbus_failed_total", "Number of handler calls that returned an error.", func(s *Stats) uint64 { return s.Failed }},
+	{"eventbus_panicked_total", "Number of handler calls that panicked.", func(s *Stats) uint64 { return s.Panicked }},
+}
+
+// writeMetrics writes the stats of every topic to w in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, stats map[string]Stats) error {
+	topics := make([]string, 0, len(stats))
+	for topic := range stats {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	bw := bufio.NewWriter(w)
+	writeHeader(bw, "eventbus_topics", "Number of topics of the eventbus.", "gauge")
+	bw.WriteString("eventbus_topics " + strconv.Itoa(len(topics)) + "\n")
+
+	for _, metric := range counterMetrics {
+		writeHeader(bw, metric.name, metric.help, "counter")
+		for _, topic := range topics {
+			s := stats[topic]
+			bw.WriteString(metric.name + "{" + topicLabel(topic) + "} " + strconv.FormatUint(metric.value(&s), 10) + "\n")
+		}
+	}
+
+	writeHeader(bw, "eventbus_queue_depth", "Number of messages waiting to be delivered.", "gauge")
+	for _, topic := range topics {
+		bw.WriteString("eventbus_queue_depth{" + topicLabel(topic) + "} " + strconv.Itoa(stats[topic].QueueDepth) + "\n")
+	}
+
+	writeHeader(bw, "eventbus_handler_duration_seconds", "Execution time of the handlers.", "histogram")
+	for _, topic := range topics {
+		writeHistogram(bw, "eventbus_handler_duration_seconds", topic, stats[topic].HandlerLatency)
+	}
+
+	writeHeader(bw, "eventbus_queue_wait_seconds", "Time spent by asynchronous messages in the queue.", "histogram")
+	for _, topic := range topics {
+		writeHistogram(bw, "eventbus_queue_wait_seconds", topic, stats[topic].QueueWait)
+	}
+	return bw.Flush()
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(w *bufio.Writer, name string, help string, typ string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeHistogram writes the cumulative buckets, the sum and the count of a histogram.
+func writeHistogram(w *bufio.Writer, name string, topic string, h Histogram) {
+	label := topicLabel(topic)
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		le := strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)
+		w.WriteString(name + "_bucket{" + label + `,le="` + le + `"} ` + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+	// The +Inf bucket and the count are the cumulative total, so that the buckets are always monotonic.
+	if len(h.Counts) > len(h.Bounds) {
+		cumulative += h.Counts[len(h.Bounds)]
+	}
+	w.WriteString(name + "_bucket{" + label + `,le="+Inf"} ` + strconv.FormatUint(cumulative, 10) + "\n")
+	w.WriteString(name + "_sum{" + label + "} " + strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64) + "\n")
+	w.WriteString(name + "_count{" + label + "} " + strconv.FormatUint(cumulative, 10) + "\n")
+}
+
+// labelEscaper escapes label values as required by the Prometheus text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// topicLabel returns the topic label of a metric.
+func topicLabel(topic string) string {
+	return `topic="` + labelEscaper.Replace(topic) + `"`
+}
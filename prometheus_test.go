@@ -0,0 +1,84 @@
+package eventbus
+
+import (
+	"bufio"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_topicLabel(t *testing.T) {
+	assert.Equal(t, `topic="a\\b\"c\nd"`, topicLabel("a\\b\"c\nd"))
+}
+
+func Test_writeMetrics(t *testing.T) {
+	stats := map[string]Stats{
+		"b": {Published: 3, Delivered: 2, Dropped: 1, QueueDepth: 4},
+		"a": {
+			Published: 1,
+			HandlerLatency: Histogram{
+				Bounds: []time.Duration{time.Millisecond, time.Second},
+				Counts: []uint64{1, 2, 1},
+				Count:  4,
+				Sum:    3 * time.Second,
+			},
+		},
+	}
+	var sb strings.Builder
+	err := writeMetrics(&sb, stats)
+	assert.Nil(t, err)
+	out := sb.String()
+
+	assert.Contains(t, out, "# TYPE eventbus_published_total counter\n")
+	assert.Contains(t, out, "eventbus_topics 2\n")
+	assert.Contains(t, out, "eventbus_published_total{topic=\"a\"} 1\neventbus_published_total{topic=\"b\"} 3\n")
+	assert.Contains(t, out, "eventbus_dropped_total{topic=\"b\"} 1\n")
+	assert.Contains(t, out, "eventbus_queue_depth{topic=\"b\"} 4\n")
+	assert.Contains(t, out, "# TYPE eventbus_handler_duration_seconds histogram\n")
+	assert.Contains(t, out, "eventbus_handler_duration_seconds_bucket{topic=\"a\",le=\"0.001\"} 1\n")
+	assert.Contains(t, out, "eventbus_handler_duration_seconds_bucket{topic=\"a\",le=\"1\"} 3\n")
+	assert.Contains(t, out, "eventbus_handler_duration_seconds_bucket{topic=\"a\",le=\"+Inf\"} 4\n")
+	assert.Contains(t, out, "eventbus_handler_duration_seconds_sum{topic=\"a\"} 3\n")
+	assert.Contains(t, out, "eventbus_handler_duration_seconds_count{topic=\"a\"} 4\n")
+}
+
+func Test_writeHistogramMonotonic(t *testing.T) {
+	var sb strings.Builder
+	w := bufio.NewWriter(&sb)
+	// Count is lower than the sum of the buckets, like a snapshot taken during an observation.
+	writeHistogram(w, "latency", "a", Histogram{
+		Bounds: []time.Duration{time.Millisecond},
+		Counts: []uint64{2, 1},
+		Count:  2,
+	})
+	assert.Nil(t, w.Flush())
+	out := sb.String()
+	assert.Contains(t, out, "latency_bucket{topic=\"a\",le=\"0.001\"} 2\n")
+	assert.Contains(t, out, "latency_bucket{topic=\"a\",le=\"+Inf\"} 3\n")
+	assert.Contains(t, out, "latency_count{topic=\"a\"} 3\n")
+}
+
+func Test_MetricsHandler(t *testing.T) {
+	bus := New()
+	err := bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+
+	server := httptest.NewServer(MetricsHandler(bus))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, prometheusContentType, resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "eventbus_delivered_total{topic=\"testtopic\"} 1\n")
+	assert.Contains(t, string(body), "eventbus_handler_duration_seconds_count{topic=\"testtopic\"} 1\n")
+	bus.Close()
+}
@@ -0,0 +1,142 @@
+package eventbus
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy defines what happens to a message published to a topic which exceeds its rate limit.
+type RateLimitPolicy int
+
+const (
+	// RateLimitBlock blocks the publisher until the message is allowed by the rate limit.
+	// The publisher is released with ErrChannelClosed if the topic is closed meanwhile,
+	// or with the error of the context given to PublishContext() or PublishSyncContext() if it is done meanwhile.
+	RateLimitBlock RateLimitPolicy = iota
+	// RateLimitError drops the message and returns ErrRateLimited.
+	RateLimitError
+	// RateLimitDrop drops the message silently, Publish() returns nil.
+	RateLimitDrop
+)
+
+// RateLimit limits the rate of the messages published to a topic to rate messages per second,
+// with bursts of up to burst messages. burst is at least 1. Zero or a negative rate means no limit.
+// The messages exceeding the limit are handled according to policy; the dropped messages
+// are reported to the observer with ErrRateLimited. The limit applies to the messages published
+// both synchronously and asynchronously, before they are deduplicated or queued.
+func RateLimit(rate float64, burst int, policy RateLimitPolicy) TopicOption {
+	return func(o *topicOptions) {
+		o.rate = rate
+		o.burst = burst
+		o.ratePolicy = policy
+	}
+}
+
+// tokenBucket is a token bucket refilled with rate tokens per second, up to burst tokens.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	// last is the last time the bucket has been refilled.
+	last time.Time
+}
+
+// newTokenBucket creates a full token bucket.
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// refill adds the tokens accumulated since the last refill. The caller must hold b.mu.
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// allow takes a token if one is available, and reports whether it did.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reserve takes a token, possibly in advance, and returns how long to wait until it is available.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a token taken by reserve and not used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// limit applies the rate limit of the channel to a message being published.
+// It reports whether the message may be published, and the error to return otherwise.
+// It must be called without holding the lock of the channel, since it may block.
+func (c *channel) limit(m *message) (bool, error) {
+	if c.limiter == nil {
+		return true, nil
+	}
+	switch c.config.ratePolicy {
+	case RateLimitError, RateLimitDrop:
+		if c.limiter.allow(time.Now()) {
+			return true, nil
+		}
+		c.drop(m.payload, ErrRateLimited)
+		if c.config.ratePolicy == RateLimitError {
+			return false, ErrRateLimited
+		}
+		return false, nil
+	}
+
+	wait := c.limiter.reserve(time.Now())
+	if wait <= 0 {
+		return true, nil
+	}
+	var done <-chan struct{}
+	if m.publishCtx != nil {
+		done = m.publishCtx.Done()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-c.stopCh:
+		c.limiter.cancel()
+		return false, ErrChannelClosed
+	case <-done:
+		c.limiter.cancel()
+		return false, m.publishCtx.Err()
+	}
+}
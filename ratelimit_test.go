@@ -0,0 +1,121 @@
+package eventbus
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, 2, now)
+	assert.True(t, b.allow(now))
+	assert.True(t, b.allow(now))
+	assert.False(t, b.allow(now))
+	assert.False(t, b.allow(now.Add(50*time.Millisecond)))
+	assert.True(t, b.allow(now.Add(100*time.Millisecond)))
+	// The bucket holds at most burst tokens.
+	assert.True(t, b.allow(now.Add(time.Hour)))
+	assert.True(t, b.allow(now.Add(time.Hour)))
+	assert.False(t, b.allow(now.Add(time.Hour)))
+
+	b = newTokenBucket(10, 0, now)
+	assert.Equal(t, time.Duration(0), b.reserve(now))
+	assert.Equal(t, 100*time.Millisecond, b.reserve(now))
+	assert.Equal(t, 200*time.Millisecond, b.reserve(now))
+	b.cancel()
+	assert.Equal(t, 200*time.Millisecond, b.reserve(now))
+}
+
+func Test_RateLimitError(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer), WithTopic("testtopic", RateLimit(1, 3, RateLimitError)))
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, bus.PublishSync("testtopic", i))
+	}
+	assert.Equal(t, ErrRateLimited, bus.PublishSync("testtopic", 3))
+	assert.Equal(t, ErrRateLimited, bus.Publish("testtopic", 4))
+	assert.Equal(t, int32(3), received.Load())
+	assert.Equal(t, uint64(2), bus.Stats()["testtopic"].Dropped)
+
+	// Other topics are not limited.
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, bus.PublishSync("other", i))
+	}
+	bus.Close()
+	assert.Contains(t, observer.Events(), "drop:testtopic:"+ErrRateLimited.Error())
+}
+
+func Test_RateLimitDrop(t *testing.T) {
+	bus := NewWithOptions(WithTopic("testtopic", RateLimit(1, 2, RateLimitDrop)))
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, bus.PublishSync("testtopic", i))
+	}
+	assert.Equal(t, int32(2), received.Load())
+	assert.Equal(t, uint64(3), bus.Stats()["testtopic"].Dropped)
+	bus.Close()
+}
+
+func Test_RateLimitBlock(t *testing.T) {
+	bus := NewWithOptions(WithTopic("testtopic", RateLimit(100, 1, RateLimitBlock)))
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, bus.PublishSync("testtopic", i))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond)
+	assert.Equal(t, int32(6), received.Load())
+	bus.Close()
+}
+
+func Test_RateLimitBlockRelease(t *testing.T) {
+	bus := NewWithOptions(WithTopic("testtopic", RateLimit(0.1, 1, RateLimitBlock)))
+	err := bus.Subscribe("testtopic", func(topic string, val int) {})
+	assert.Nil(t, err)
+	assert.Nil(t, bus.PublishSync("testtopic", 1))
+
+	// A blocked publisher is released when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, bus.PublishSyncContext(ctx, "testtopic", 2))
+
+	// The context given to PublishContext() also releases the publisher,
+	// although the handlers receive a context detached from its cancellation.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, bus.PublishContext(ctx, "testtopic", 2))
+
+	// A blocked publisher is released when the topic is closed.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- bus.Publish("testtopic", 3)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	bus.Close()
+	select {
+	case err := <-errCh:
+		assert.Equal(t, ErrChannelClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("the publisher has not been released")
+	}
+}
@@ -0,0 +1,30 @@
+package eventbus
+
+// Retained makes a topic remember its last message and deliver it to every new handler,
+// like the retained messages of MQTT. Subscribe() calls the new handler with the retained message
+// before returning, and the live messages are delivered to the handler only afterwards.
+// A retained topic is stateful, see TopicOption. RemoveTopic() discards the retained message.
+func Retained() TopicOption {
+	return func(o *topicOptions) {
+		o.retained = true
+	}
+}
+
+// Retained returns the payload of the message retained by the topic,
+// and reports whether the topic has retained a message.
+func (e *EventBus) Retained(topic string) (any, bool) {
+	ch, ok := e.channels.Load(topic)
+	if !ok {
+		return nil, false
+	}
+	return ch.(*channel).retainedPayload()
+}
+
+// retainedPayload returns the payload of the retained message, if any.
+func (c *channel) retainedPayload() (any, bool) {
+	m := c.retained.Load()
+	if m == nil {
+		return nil, false
+	}
+	return m.payload, true
+}
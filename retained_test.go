@@ -0,0 +1,132 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Retained(t *testing.T) {
+	bus := NewWithOptions(WithTopic("config", Retained()))
+	_, ok := bus.Retained("config")
+	assert.False(t, ok)
+
+	err := bus.PublishSync("config", 1)
+	assert.Nil(t, err)
+	err = bus.PublishSync("config", 2)
+	assert.Nil(t, err)
+	payload, ok := bus.Retained("config")
+	assert.True(t, ok)
+	assert.Equal(t, 2, payload)
+
+	var received []int
+	err = bus.Subscribe("config", func(topic string, val int) {
+		received = append(received, val)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2}, received)
+
+	err = bus.PublishSync("config", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 3}, received)
+
+	// Other topics do not retain messages.
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	_, ok = bus.Retained("testtopic")
+	assert.False(t, ok)
+	var others []int
+	err = bus.Subscribe("testtopic", func(topic string, val int) {
+		others = append(others, val)
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, others)
+	bus.Close()
+}
+
+func Test_RetainedEnvelope(t *testing.T) {
+	bus := NewWithOptions(WithTopic("config", Retained()), WithSource("test"))
+	err := bus.Publish("config", "value")
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		_, ok := bus.Retained("config")
+		return ok
+	}, time.Second, time.Millisecond)
+
+	var received *Envelope[string]
+	err = bus.Subscribe("config", func(env *Envelope[string]) {
+		received = env
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, received)
+	assert.Equal(t, "value", received.Payload)
+	assert.Equal(t, "config", received.Topic)
+	assert.Equal(t, "test", received.Source)
+	bus.Close()
+}
+
+func Test_RetainedOrdering(t *testing.T) {
+	bus := NewWithOptions(WithTopic("config", Retained()), WithBufferSize(100))
+	err := bus.PublishSync("config", 0)
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	var received []int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 100; i++ {
+			err := bus.Publish("config", i)
+			assert.Nil(t, err)
+		}
+	}()
+	err = bus.Subscribe("config", func(topic string, val int) {
+		mu.Lock()
+		received = append(received, val)
+		mu.Unlock()
+	})
+	assert.Nil(t, err)
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		return bus.QueueDepth("config") == 0
+	}, time.Second, time.Millisecond)
+	bus.Close()
+
+	// The retained message is delivered first, followed by the live messages without gaps or duplicates.
+	mu.Lock()
+	defer mu.Unlock()
+	assert.NotEmpty(t, received)
+	for i, val := range received {
+		assert.Equal(t, received[0]+i, val)
+	}
+	assert.Equal(t, 100, received[len(received)-1])
+}
+
+func Test_RetainedTopicLifecycle(t *testing.T) {
+	bus := NewWithOptions(
+		WithTopic("config", Retained()),
+		WithNoSubscriberPolicy(NoSubscriberDrop),
+		WithAutoRemoveTopics(),
+		WithTopicIdleTTL(20*time.Millisecond),
+	)
+	err := bus.PublishSync("config", 1)
+	assert.Nil(t, err)
+	err = bus.Subscribe("config", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.Unsubscribe("config", busHandlerOne)
+	assert.Nil(t, err)
+	time.Sleep(60 * time.Millisecond)
+
+	payload, ok := bus.Retained("config")
+	assert.True(t, ok)
+	assert.Equal(t, 1, payload)
+
+	err = bus.RemoveTopic("config")
+	assert.Nil(t, err)
+	_, ok = bus.Retained("config")
+	assert.False(t, ok)
+	bus.Close()
+}
@@ -0,0 +1,179 @@
+package eventbus
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JobOption configures a periodic job started by Schedule() or Every().
+type JobOption func(*Job)
+
+// WithJitter delays each run of a periodic job by a random duration between zero and max,
+// so that the jobs of several processes scheduled at the same time do not run all at once.
+func WithJitter(max time.Duration) JobOption {
+	return func(j *Job) {
+		j.jitter = max
+	}
+}
+
+// Job is a periodic job publishing to a topic, started by Schedule() or Every().
+// Each run calls the payload function, then publishes the payload synchronously, so that a run
+// lasts until the handlers of the topic return. A run due while the previous run is still running
+// is skipped. The payloads which can't be published are reported to the observer with OnDrop().
+type Job struct {
+	e       *EventBus
+	topic   string
+	payload func() any
+	// next returns the time of the first run after the given time, or the zero time if there is none.
+	next   func(t time.Time) time.Time
+	jitter time.Duration
+
+	// running is true while a run is in progress.
+	running  atomic.Bool
+	skipped  atomic.Uint64
+	runs     sync.WaitGroup
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// Schedule starts a job publishing the payload returned by payload to a topic at the times matching
+// a cron expression, evaluated in the local time zone. The expression has 6 fields separated by spaces:
+// second, minute, hour, day of the month, month and day of the week, like "0 */5 * * * *" for every
+// five minutes. Each field is "*", a value, a range "a-b", or a list of them separated by commas,
+// each optionally followed by a step "/n"; the months and the days of the week also accept names
+// like "JAN" or "MON". The job is stopped by Job.Stop() or when the eventbus is closed.
+// Returns ErrInvalidSchedule if the expression is malformed, and ErrChannelClosed if the eventbus is closed.
+func (e *EventBus) Schedule(spec string, topic string, payload func() any, opts ...JobOption) (*Job, error) {
+	s, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	return e.startJob(topic, payload, s.next, opts)
+}
+
+// Every starts a job publishing the payload returned by payload to a topic every interval,
+// the first time one interval after the call. The job is stopped by Job.Stop() or when the eventbus is closed.
+// Returns ErrInvalidSchedule if interval is not positive, and ErrChannelClosed if the eventbus is closed.
+func (e *EventBus) Every(interval time.Duration, topic string, payload func() any, opts ...JobOption) (*Job, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidSchedule
+	}
+	next := func(t time.Time) time.Time {
+		return t.Add(interval)
+	}
+	return e.startJob(topic, payload, next, opts)
+}
+
+// startJob starts a periodic job and registers it to be stopped when the eventbus is closed.
+func (e *EventBus) startJob(topic string, payload func() any, next func(time.Time) time.Time, opts []JobOption) (*Job, error) {
+	j := &Job{
+		e:       e,
+		topic:   topic,
+		payload: payload,
+		next:    next,
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	e.RLock()
+	defer e.RUnlock()
+	if e.closed || e.jobsStopped {
+		return nil, ErrChannelClosed
+	}
+	e.jobs.Store(j, struct{}{})
+	go j.loop()
+	return j, nil
+}
+
+// stopJobs stops the periodic jobs of the eventbus, then waits for their runs in progress to return,
+// at most until the deadline if it is not zero. It returns the topics of the jobs still running.
+func (e *EventBus) stopJobs(deadline time.Time) []string {
+	e.Lock()
+	e.jobsStopped = true
+	e.Unlock()
+	var jobs []*Job
+	e.jobs.Range(func(j any, _ any) bool {
+		jobs = append(jobs, j.(*Job))
+		return true
+	})
+	for _, j := range jobs {
+		j.Stop()
+	}
+
+	var topics []string
+	for _, j := range jobs {
+		if !waitUntil(&j.runs, deadline) {
+			topics = append(topics, j.topic)
+		}
+	}
+	return topics
+}
+
+// Stop stops the job and waits for its timer goroutine to exit.
+// A run in progress is not interrupted. Stop may be called several times.
+func (j *Job) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stopCh)
+		j.e.jobs.Delete(j)
+	})
+	<-j.doneCh
+}
+
+// Skipped returns the number of runs skipped because the previous run was still running.
+func (j *Job) Skipped() uint64 {
+	return j.skipped.Load()
+}
+
+// loop waits for the time of each run and starts it, until the job is stopped.
+func (j *Job) loop() {
+	defer close(j.doneCh)
+	last := time.Now()
+	for {
+		at := j.next(last)
+		if at.IsZero() {
+			return
+		}
+		if now := time.Now(); at.Before(now) {
+			// The runs missed while the system was suspended are not caught up.
+			at = j.next(now)
+			if at.IsZero() {
+				return
+			}
+		}
+		last = at
+
+		delay := time.Until(at)
+		if j.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(j.jitter)))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-j.stopCh:
+			timer.Stop()
+			return
+		}
+
+		if !j.running.CompareAndSwap(false, true) {
+			j.skipped.Add(1)
+			continue
+		}
+		j.runs.Add(1)
+		go j.run()
+	}
+}
+
+// run publishes a payload to the topic of the job.
+// The observer is notified with OnDrop() if the payload can't be published.
+func (j *Job) run() {
+	defer j.runs.Done()
+	defer j.running.Store(false)
+	payload := j.payload()
+	j.e.notifyPublishError(j.topic, payload, j.e.PublishSync(j.topic, payload))
+}
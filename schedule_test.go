@@ -0,0 +1,116 @@
+package eventbus
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Every(t *testing.T) {
+	bus := New()
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	var n atomic.Int32
+	job, err := bus.Every(5*time.Millisecond, "testtopic", func() any {
+		return int(n.Add(1))
+	})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return received.Load() >= 3 }, time.Second, time.Millisecond)
+	job.Stop()
+	job.Stop()
+	stopped := received.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, received.Load())
+
+	_, err = bus.Every(0, "testtopic", func() any { return 0 })
+	assert.Equal(t, ErrInvalidSchedule, err)
+	bus.Close()
+}
+
+func Test_EverySkipIfRunning(t *testing.T) {
+	bus := New()
+	release := make(chan struct{})
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+		<-release
+	})
+	assert.Nil(t, err)
+
+	job, err := bus.Every(time.Millisecond, "testtopic", func() any { return 1 }, WithJitter(time.Millisecond))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return job.Skipped() >= 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), received.Load())
+	close(release)
+	job.Stop()
+	bus.Close()
+}
+
+func Test_Schedule(t *testing.T) {
+	bus := New()
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+	})
+	assert.Nil(t, err)
+
+	_, err = bus.Schedule("* * * *", "testtopic", func() any { return 1 })
+	assert.Equal(t, ErrInvalidSchedule, err)
+	_, err = bus.Schedule("* * * * * *", "testtopic", func() any { return 1 })
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return received.Load() >= 1 }, 3*time.Second, 10*time.Millisecond)
+
+	// Close stops the jobs.
+	bus.Close()
+	_, err = bus.Schedule("* * * * * *", "testtopic", func() any { return 1 })
+	assert.Equal(t, ErrChannelClosed, err)
+	_, err = bus.Every(time.Second, "testtopic", func() any { return 1 })
+	assert.Equal(t, ErrChannelClosed, err)
+	assert.Equal(t, 0, int(bus.jobs.Len()))
+}
+
+func Test_EveryCloseTimeout(t *testing.T) {
+	bus := New()
+	release := make(chan struct{})
+	var received atomic.Int32
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		received.Add(1)
+		<-release
+	})
+	assert.Nil(t, err)
+	_, err = bus.Every(5*time.Millisecond, "testtopic", func() any { return 1 })
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return received.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The close timeout covers the runs in progress.
+	start := time.Now()
+	err = bus.CloseTimeout(50 * time.Millisecond)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	var timeoutErr *CloseTimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, []string{"testtopic"}, timeoutErr.Topics)
+	close(release)
+}
+
+func Test_EveryError(t *testing.T) {
+	observer := &recordingObserver{}
+	bus := NewWithOptions(WithObserver(observer), WithDataDir(t.TempDir()), WithTopic("orders", Durable[durableEvent]()))
+	job, err := bus.Every(5*time.Millisecond, "orders", func() any { return 1 })
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		for _, e := range observer.Events() {
+			if e == "drop:orders:"+ErrPayloadType.Error() {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	job.Stop()
+	bus.Close()
+}
@@ -1,5 +1,7 @@
 package eventbus
 
+import "context"
+
 var (
 	// singleton is a pointer to a unbuffered EventBus instance, which will be created when necessary.
 	singleton *EventBus
@@ -27,8 +29,8 @@ func Unsubscribe(topic string, handler any) error {
 // Subscribe subscribes to a topic, return an error if the handler is not a function.
 // The handler must have two parameters: the first parameter must be a string,
 // and the type of the handler's second parameter must be consistent with the type of the payload in `Publish()`
-func Subscribe(topic string, handler any) error {
-	return singleton.Subscribe(topic, handler)
+func Subscribe(topic string, handler any, opts ...SubscribeOption) error {
+	return singleton.Subscribe(topic, handler, opts...)
 }
 
 // Publish triggers the handlers defined for a topic. The `payload` argument will be passed to the handler.
@@ -43,6 +45,26 @@ func PublishSync(topic string, payload any) error {
 	return singleton.PublishSync(topic, payload)
 }
 
+// PublishContext is like Publish, but handlers accepting a context.Context as their first parameter receive the values of ctx.
+func PublishContext(ctx context.Context, topic string, payload any) error {
+	return singleton.PublishContext(ctx, topic, payload)
+}
+
+// PublishSyncContext is a synchronous version of PublishContext.
+func PublishSyncContext(ctx context.Context, topic string, payload any) error {
+	return singleton.PublishSyncContext(ctx, topic, payload)
+}
+
+// PublishEnvelope publishes a message with custom metadata to the topic of the envelope asynchronously.
+func PublishEnvelope(env AnyEnvelope) error {
+	return singleton.PublishEnvelope(env)
+}
+
+// PublishEnvelopeSync is a synchronous version of PublishEnvelope.
+func PublishEnvelopeSync(env AnyEnvelope) error {
+	return singleton.PublishEnvelopeSync(env)
+}
+
 // Close closes the singleton instance of EventBus.
 func Close() {
 	if singleton != nil {
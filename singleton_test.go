@@ -134,3 +134,19 @@ func BenchmarkSingletonPublishSync(b *testing.B) {
 	wg.Wait()
 	Close()
 }
+
+func Test_SingletonPublishEnvelope(t *testing.T) {
+	ResetSingleton()
+	var payloads []int
+	err := Subscribe("testtopic", func(env *Envelope[int]) {
+		payloads = append(payloads, env.Payload)
+	})
+	assert.Nil(t, err)
+
+	err = PublishEnvelopeSync(&Envelope[int]{Topic: "testtopic", Payload: 1})
+	assert.Nil(t, err)
+	err = PublishEnvelope(&Envelope[int]{Topic: "testtopic", Payload: 2})
+	assert.Nil(t, err)
+	Close()
+	assert.Equal(t, []int{1, 2}, payloads)
+}
@@ -0,0 +1,181 @@
+package eventbus
+
+import (
+	"sort"
+	"time"
+)
+
+// minReapInterval is the minimum interval between two scans for idle topics.
+const minReapInterval = 10 * time.Millisecond
+
+// TopicOption configures a topic of an EventBus, see WithTopic().
+//
+// The topics configured with Retained(), History() or Durable() are stateful: they keep a state
+// across their messages. The deliveries of a stateful topic are serialized, even with WithWorkers().
+// A stateful topic is never removed by WithAutoRemoveTopics() or WithTopicIdleTTL(),
+// and the NoSubscriberPolicy does not apply to it. The handlers of a stateful topic
+// must not subscribe or publish synchronously to the same topic.
+type TopicOption func(*topicOptions)
+
+// topicOptions holds the settings of a topic.
+type topicOptions struct {
+	retained    bool
+	historySize int
+	historyAge  time.Duration
+	durable     *durableConfig
+	// retentionBytes and retentionAge limit the write-ahead log of a durable topic.
+	retentionBytes int64
+	retentionAge   time.Duration
+	// dedup is true if the topic drops the duplicate messages, see Dedup().
+	dedup     bool
+	dedupSize int
+	dedupAge  time.Duration
+	dedupKey  func(payload any) string
+	// rate, burst and ratePolicy limit the rate of the messages published to the topic, see RateLimit().
+	rate       float64
+	burst      int
+	ratePolicy RateLimitPolicy
+}
+
+// defaultTopicOptions are the settings of the topics not configured with WithTopic().
+var defaultTopicOptions topicOptions
+
+// WithTopic configures a topic with the given options.
+// It may be used several times for the same topic, the options are then combined.
+func WithTopic(topic string, opts ...TopicOption) Option {
+	return func(o *options) {
+		if o.topics == nil {
+			o.topics = make(map[string]*topicOptions)
+		}
+		t, ok := o.topics[topic]
+		if !ok {
+			t = &topicOptions{}
+			o.topics[topic] = t
+		}
+		for _, opt := range opts {
+			opt(t)
+		}
+	}
+}
+
+// hasHistory reports whether the topic keeps a history of its messages.
+func (t *topicOptions) hasHistory() bool {
+	return t.historySize > 0 || t.historyAge > 0
+}
+
+// stateful reports whether the topic keeps a state across its messages.
+// The deliveries of a stateful topic are serialized, and a stateful topic is never removed automatically.
+func (t *topicOptions) stateful() bool {
+	return t.retained || t.hasHistory() || t.durable != nil
+}
+
+// topicOptions returns the settings of a topic.
+func (o *options) topicOptions(topic string) *topicOptions {
+	if t, ok := o.topics[topic]; ok {
+		return t
+	}
+	return &defaultTopicOptions
+}
+
+// RemoveTopic removes a topic from the eventbus, unsubscribing all its handlers and
+// discarding the messages not yet delivered. It waits for the goroutine dispatching
+// messages of the topic to exit, so it must not be called from within a handler of the topic.
+// Returns ErrTopicNotFound if the topic does not exist.
+func (e *EventBus) RemoveTopic(topic string) error {
+	ch, ok := e.channels.LoadAndDelete(topic)
+	if !ok {
+		return ErrTopicNotFound
+	}
+	ch.(*channel).close()
+	return nil
+}
+
+// Topics returns the sorted names of the topics currently known by the eventbus.
+func (e *EventBus) Topics() []string {
+	topics := make([]string, 0, e.channels.Len())
+	e.channels.Range(func(key any, ch any) bool {
+		topics = append(topics, key.(string))
+		return true
+	})
+	sort.Strings(topics)
+	return topics
+}
+
+// SubscriberCount returns the number of handlers subscribed to the topic, including the members of its consumer groups.
+func (e *EventBus) SubscriberCount(topic string) int {
+	ch, ok := e.channels.Load(topic)
+	if !ok {
+		return 0
+	}
+	return ch.(*channel).subscriberCount()
+}
+
+// HasSubscribers reports whether at least one handler is subscribed to the topic.
+func (e *EventBus) HasSubscribers(topic string) bool {
+	return e.SubscriberCount(topic) > 0
+}
+
+// QueueDepth returns the number of messages published asynchronously
+// to the topic and waiting to be delivered to its handlers.
+func (e *EventBus) QueueDepth(topic string) int {
+	ch, ok := e.channels.Load(topic)
+	if !ok {
+		return 0
+	}
+	return ch.(*channel).queueDepth()
+}
+
+// removeIfUnused removes the channel from the eventbus if it has no handlers
+// and has not been used for at least idle. It does not wait for the
+// goroutine of the channel to exit, so it is safe to call from within a handler.
+func (e *EventBus) removeIfUnused(ch *channel, idle time.Duration) {
+	if !ch.stopIfUnused(idle) {
+		return
+	}
+	e.channels.CompareAndDelete(ch.topic, ch)
+	e.retired.Store(ch, struct{}{})
+	go func() {
+		ch.wait(0)
+		e.retired.Delete(ch)
+	}()
+}
+
+// startReaper starts a goroutine which periodically removes the topics
+// that have been idle for at least ttl.
+func (e *EventBus) startReaper(ttl time.Duration) {
+	interval := ttl / 2
+	if interval < minReapInterval {
+		interval = minReapInterval
+	}
+	reaperCh := make(chan struct{})
+	e.reaperCh = reaperCh
+	e.reaperWg.Add(1)
+	go func() {
+		defer e.reaperWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.channels.Range(func(key any, ch any) bool {
+					e.removeIfUnused(ch.(*channel), ttl)
+					return true
+				})
+			case <-reaperCh:
+				return
+			}
+		}
+	}()
+}
+
+// stopReaper stops the goroutine started by startReaper and waits for it to exit.
+func (e *EventBus) stopReaper() {
+	e.Lock()
+	reaperCh := e.reaperCh
+	e.reaperCh = nil
+	e.Unlock()
+	if reaperCh != nil {
+		close(reaperCh)
+		e.reaperWg.Wait()
+	}
+}
@@ -0,0 +1,135 @@
+package eventbus
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EventBusRemoveTopic(t *testing.T) {
+	bus := New()
+	err := bus.RemoveTopic("testtopic")
+	assert.Equal(t, ErrTopicNotFound, err)
+
+	err = bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	old, _ := bus.channels.Load("testtopic")
+
+	err = bus.RemoveTopic("testtopic")
+	assert.Nil(t, err)
+	assert.True(t, old.(*channel).exited())
+	_, ok := bus.channels.Load("testtopic")
+	assert.False(t, ok)
+
+	err = bus.Unsubscribe("testtopic", busHandlerOne)
+	assert.Equal(t, ErrNoSubscriber, err)
+
+	var count atomic.Int32
+	err = bus.Subscribe("testtopic", func(topic string, val int) {
+		count.Add(1)
+	})
+	assert.Nil(t, err)
+	err = bus.PublishSync("testtopic", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), count.Load())
+	bus.Close()
+}
+
+func Test_EventBusAutoRemoveTopics(t *testing.T) {
+	bus := NewWithOptions(WithAutoRemoveTopics())
+	err := bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", busHandlerTwo)
+	assert.Nil(t, err)
+	old, _ := bus.channels.Load("testtopic")
+
+	err = bus.Unsubscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	_, ok := bus.channels.Load("testtopic")
+	assert.True(t, ok)
+
+	err = bus.Unsubscribe("testtopic", busHandlerTwo)
+	assert.Nil(t, err)
+	_, ok = bus.channels.Load("testtopic")
+	assert.False(t, ok)
+	bus.Close()
+	assert.True(t, old.(*channel).exited())
+}
+
+func Test_EventBusAutoRemoveFromHandler(t *testing.T) {
+	bus := NewWithOptions(WithAutoRemoveTopics())
+	done := make(chan struct{})
+	var handler func(topic string, val int)
+	handler = func(topic string, val int) {
+		bus.Unsubscribe(topic, handler)
+		close(done)
+	}
+	err := bus.Subscribe("testtopic", handler)
+	assert.Nil(t, err)
+	err = bus.Publish("testtopic", 1)
+	assert.Nil(t, err)
+	<-done
+	bus.Close()
+	_, ok := bus.channels.Load("testtopic")
+	assert.False(t, ok)
+}
+
+func Test_EventBusTopicIdleTTL(t *testing.T) {
+	bus := NewWithOptions(WithTopicIdleTTL(20 * time.Millisecond))
+	err := bus.Publish("idletopic", 1)
+	assert.Nil(t, err)
+	err = bus.Subscribe("usedtopic", busHandlerOne)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, ok := bus.channels.Load("idletopic")
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+	_, ok := bus.channels.Load("usedtopic")
+	assert.True(t, ok)
+
+	err = bus.Publish("idletopic", 1)
+	assert.Nil(t, err)
+	bus.Close()
+	assert.Nil(t, bus.reaperCh)
+}
+
+func Test_EventBusIntrospection(t *testing.T) {
+	bus := NewBuffered(10)
+	assert.Equal(t, []string{}, bus.Topics())
+	assert.Equal(t, 0, bus.SubscriberCount("testtopic"))
+	assert.False(t, bus.HasSubscribers("testtopic"))
+	assert.Equal(t, 0, bus.QueueDepth("testtopic"))
+
+	release := make(chan struct{})
+	err := bus.Subscribe("testtopic", func(topic string, val int) {
+		<-release
+	})
+	assert.Nil(t, err)
+	err = bus.Subscribe("testtopic", busHandlerOne)
+	assert.Nil(t, err)
+	err = bus.Publish("another", 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"another", "testtopic"}, bus.Topics())
+	assert.Equal(t, 2, bus.SubscriberCount("testtopic"))
+	assert.True(t, bus.HasSubscribers("testtopic"))
+	assert.False(t, bus.HasSubscribers("another"))
+
+	for i := 0; i < 4; i++ {
+		err = bus.Publish("testtopic", i)
+		assert.Nil(t, err)
+	}
+	// The first message is blocked in the handler, the others are queued.
+	assert.Eventually(t, func() bool {
+		return bus.QueueDepth("testtopic") == 3
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return bus.QueueDepth("testtopic") == 0
+	}, time.Second, time.Millisecond)
+	bus.Close()
+}
@@ -0,0 +1,122 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// TraceparentHeader is the header carrying the W3C trace context.
+	TraceparentHeader = "traceparent"
+	// TracestateHeader is the header carrying the vendor-specific W3C trace state.
+	TracestateHeader = "tracestate"
+)
+
+// SpanContext identifies a span of a distributed trace, as defined by the W3C Trace Context specification.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Flags are the trace flags, 0x01 meaning the trace is sampled.
+	Flags byte
+	// State is the vendor-specific trace state, propagated as is.
+	State string
+}
+
+// IsValid reports whether the trace ID and the span ID are both non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent returns the value of the traceparent header of the span context.
+func (sc SpanContext) Traceparent() string {
+	var b strings.Builder
+	b.Grow(55)
+	b.WriteString("00-")
+	b.WriteString(hex.EncodeToString(sc.TraceID[:]))
+	b.WriteByte('-')
+	b.WriteString(hex.EncodeToString(sc.SpanID[:]))
+	b.WriteByte('-')
+	b.WriteString(hex.EncodeToString([]byte{sc.Flags}))
+	return b.String()
+}
+
+// ParseTraceparent parses the value of a traceparent header.
+// Returns ErrInvalidTraceparent if the value is malformed.
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	// version-traceid-spanid-flags, future versions may append fields.
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, ErrInvalidTraceparent
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, ErrInvalidTraceparent
+	}
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return sc, ErrInvalidTraceparent
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return sc, ErrInvalidTraceparent
+	}
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// decodeHex decodes a lowercase hexadecimal string filling exactly dst.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// spanContextKey is the context key of the SpanContext.
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying the span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// TraceContext is a Propagator carrying the SpanContext of a context.Context
+// in the traceparent and tracestate headers, as defined by the W3C Trace Context specification.
+type TraceContext struct{}
+
+// Inject implements Propagator.
+func (TraceContext) Inject(ctx context.Context, headers map[string]string) {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok || !sc.IsValid() {
+		return
+	}
+	headers[TraceparentHeader] = sc.Traceparent()
+	if sc.State != "" {
+		headers[TracestateHeader] = sc.State
+	}
+}
+
+// Extract implements Propagator.
+// ctx is returned unchanged if the headers do not carry a valid traceparent.
+func (TraceContext) Extract(ctx context.Context, headers map[string]string) context.Context {
+	value, ok := headers[TraceparentHeader]
+	if !ok {
+		return ctx
+	}
+	sc, err := ParseTraceparent(value)
+	if err != nil {
+		return ctx
+	}
+	sc.State = headers[TracestateHeader]
+	return ContextWithSpanContext(ctx, sc)
+}
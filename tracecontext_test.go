@@ -0,0 +1,58 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseTraceparent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(value)
+	assert.Nil(t, err)
+	assert.True(t, sc.IsValid())
+	assert.Equal(t, byte(1), sc.Flags)
+	assert.Equal(t, value, sc.Traceparent())
+
+	// Future versions may append fields.
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.Nil(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, err = ParseTraceparent(invalid)
+		assert.Equal(t, ErrInvalidTraceparent, err, invalid)
+	}
+}
+
+func Test_TraceContextPropagator(t *testing.T) {
+	var propagator Propagator = TraceContext{}
+	sc := SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Flags: 1, State: "vendor=value"}
+
+	headers := map[string]string{}
+	propagator.Inject(context.Background(), headers)
+	assert.Empty(t, headers)
+
+	propagator.Inject(ContextWithSpanContext(context.Background(), sc), headers)
+	assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-01", headers[TraceparentHeader])
+	assert.Equal(t, "vendor=value", headers[TracestateHeader])
+
+	ctx := propagator.Extract(context.Background(), headers)
+	extracted, ok := SpanContextFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, sc, extracted)
+
+	ctx = context.Background()
+	assert.Equal(t, ctx, propagator.Extract(ctx, map[string]string{TraceparentHeader: "invalid"}))
+	assert.Equal(t, ctx, propagator.Extract(ctx, map[string]string{}))
+}
@@ -0,0 +1,535 @@
+package eventbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// walSegmentExt is the extension of the segment files of a write-ahead log.
+	walSegmentExt = ".wal"
+	// walFrameHeaderSize is the size of the header of a record: the length and the CRC of its body.
+	walFrameHeaderSize = 8
+	// walMaxRecordSize is the maximum size of the body of a record,
+	// guarding against corrupted lengths.
+	walMaxRecordSize = 1 << 30
+)
+
+// walCRCTable is the table used to compute the checksums of the records.
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walConfig holds the settings of a write-ahead log.
+type walConfig struct {
+	// segmentSize is the size above which a new segment is started.
+	segmentSize int64
+	// maxBytes and maxAge are the retention limits, zero means no limit.
+	maxBytes int64
+	maxAge   time.Duration
+	// fsync flushes every record to the disk before returning.
+	fsync bool
+}
+
+// walRecord is a message stored in a write-ahead log.
+type walRecord struct {
+	seq     uint64
+	time    time.Time
+	id      string
+	source  string
+	headers map[string]string
+	payload []byte
+}
+
+// marshal returns the frame of the record: the length and the CRC of the body, followed by the body.
+func (r *walRecord) marshal() []byte {
+	buf := make([]byte, walFrameHeaderSize, walFrameHeaderSize+32+len(r.id)+len(r.source)+len(r.payload))
+	buf = binary.BigEndian.AppendUint64(buf, r.seq)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(r.time.UnixNano()))
+	buf = appendString(buf, r.id)
+	buf = appendString(buf, r.source)
+	buf = binary.AppendUvarint(buf, uint64(len(r.headers)))
+	for k, v := range r.headers {
+		buf = appendString(buf, k)
+		buf = appendString(buf, v)
+	}
+	buf = append(buf, r.payload...)
+
+	body := buf[walFrameHeaderSize:]
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(body, walCRCTable))
+	return buf
+}
+
+// unmarshalWALRecord decodes the body of a record.
+func unmarshalWALRecord(body []byte) (*walRecord, error) {
+	if len(body) < 16 {
+		return nil, ErrCorruptedLog
+	}
+	r := &walRecord{
+		seq:  binary.BigEndian.Uint64(body[0:8]),
+		time: time.Unix(0, int64(binary.BigEndian.Uint64(body[8:16]))),
+	}
+	rest := body[16:]
+	var ok bool
+	if r.id, rest, ok = readString(rest); !ok {
+		return nil, ErrCorruptedLog
+	}
+	if r.source, rest, ok = readString(rest); !ok {
+		return nil, ErrCorruptedLog
+	}
+	n, size := binary.Uvarint(rest)
+	if size <= 0 || n > uint64(len(rest)) {
+		return nil, ErrCorruptedLog
+	}
+	rest = rest[size:]
+	if n > 0 {
+		r.headers = make(map[string]string, n)
+	}
+	for i := uint64(0); i < n; i++ {
+		var k, v string
+		if k, rest, ok = readString(rest); !ok {
+			return nil, ErrCorruptedLog
+		}
+		if v, rest, ok = readString(rest); !ok {
+			return nil, ErrCorruptedLog
+		}
+		r.headers[k] = v
+	}
+	r.payload = rest
+	return r, nil
+}
+
+// appendString appends a length-prefixed string to buf.
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// readString reads a length-prefixed string from buf, and returns the rest of buf.
+func readString(buf []byte) (string, []byte, bool) {
+	n, size := binary.Uvarint(buf)
+	if size <= 0 || n > uint64(len(buf)-size) {
+		return "", nil, false
+	}
+	end := size + int(n)
+	return string(buf[size:end]), buf[end:], true
+}
+
+// readFrame reads the record starting at offset in a segment file whose valid data ends at limit.
+// It returns the record and the size of its frame, or ErrCorruptedLog if the frame is invalid or incomplete.
+func readFrame(file *os.File, offset int64, limit int64) (*walRecord, int64, error) {
+	if limit-offset < walFrameHeaderSize {
+		return nil, 0, ErrCorruptedLog
+	}
+	var header [walFrameHeaderSize]byte
+	if _, err := file.ReadAt(header[:], offset); err != nil {
+		return nil, 0, readError(err)
+	}
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	if length > walMaxRecordSize || limit-offset-walFrameHeaderSize < length {
+		return nil, 0, ErrCorruptedLog
+	}
+	body := make([]byte, length)
+	if _, err := file.ReadAt(body, offset+walFrameHeaderSize); err != nil {
+		return nil, 0, readError(err)
+	}
+	if crc32.Checksum(body, walCRCTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, ErrCorruptedLog
+	}
+	r, err := unmarshalWALRecord(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, walFrameHeaderSize + length, nil
+}
+
+// readError converts the unexpected end of a segment file to ErrCorruptedLog.
+func readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrCorruptedLog
+	}
+	return err
+}
+
+// walSegment describes a segment file of a write-ahead log.
+type walSegment struct {
+	path string
+	// firstSeq is the sequence number of the first record of the segment,
+	// and lastSeq the one of the last record, or firstSeq-1 if the segment is empty.
+	firstSeq uint64
+	lastSeq  uint64
+	// size is the size of the valid records of the segment.
+	size int64
+	// lastTime is the time of the last record of the segment.
+	lastTime time.Time
+}
+
+// wal is a write-ahead log made of segment files stored in a directory.
+// Each segment is named after the sequence number of its first record.
+// The records are appended to the last segment, and a new segment is started
+// when the last one exceeds the segment size.
+type wal struct {
+	mu       sync.Mutex
+	dir      string
+	cfg      walConfig
+	segments []*walSegment
+	// file is the last segment, opened for appending.
+	file   *os.File
+	closed bool
+}
+
+// openWAL opens the write-ahead log stored in dir, creating it if needed.
+// The incomplete or corrupted records at the end of the segments, left by a crash, are discarded.
+func openWAL(dir string, cfg walConfig) (*wal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wal{dir: dir, cfg: cfg}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, walSegmentExt) {
+			continue
+		}
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(name, walSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments = append(w.segments, &walSegment{path: filepath.Join(dir, name), firstSeq: firstSeq})
+	}
+	sort.Slice(w.segments, func(i, j int) bool {
+		return w.segments[i].firstSeq < w.segments[j].firstSeq
+	})
+	for _, seg := range w.segments {
+		if err := seg.scan(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(w.segments) == 0 {
+		if err := w.create(1); err != nil {
+			return nil, err
+		}
+	} else {
+		last := w.segments[len(w.segments)-1]
+		file, err := os.OpenFile(last.path, os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		// Discard the incomplete record written during a crash, if any.
+		if err := file.Truncate(last.size); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if _, err := file.Seek(last.size, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		w.file = file
+	}
+	w.enforceRetention(time.Now())
+	return w, nil
+}
+
+// scan reads the records of the segment to find its last sequence number and the size of its valid records.
+func (s *walSegment) scan() error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	s.lastSeq = s.firstSeq - 1
+	s.size = 0
+	for s.size < info.Size() {
+		r, n, err := readFrame(file, s.size, info.Size())
+		if err == ErrCorruptedLog {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		s.lastSeq = r.seq
+		s.lastTime = r.time
+		s.size += n
+	}
+	if s.lastTime.IsZero() {
+		s.lastTime = info.ModTime()
+	}
+	return nil
+}
+
+// create starts a new segment whose first record has the sequence number firstSeq.
+// The caller must hold the lock, except while opening the log.
+func (w *wal) create(firstSeq uint64) error {
+	path := filepath.Join(w.dir, fmt.Sprintf("%020d%s", firstSeq, walSegmentExt))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file = file
+	w.segments = append(w.segments, &walSegment{
+		path:     path,
+		firstSeq: firstSeq,
+		lastSeq:  firstSeq - 1,
+		lastTime: time.Now(),
+	})
+	return nil
+}
+
+// append assigns the next sequence number to the record and appends it to the log.
+func (w *wal) append(r *walRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrChannelClosed
+	}
+
+	last := w.segments[len(w.segments)-1]
+	if last.size >= w.cfg.segmentSize && last.size > 0 {
+		if err := w.create(last.lastSeq + 1); err != nil {
+			return err
+		}
+		w.enforceRetention(time.Now())
+		last = w.segments[len(w.segments)-1]
+	}
+
+	r.seq = last.lastSeq + 1
+	frame := r.marshal()
+	if _, err := w.file.Write(frame); err != nil {
+		// Forget the partially written record, it is overwritten by the next one.
+		w.file.Truncate(last.size)
+		w.file.Seek(last.size, io.SeekStart)
+		return err
+	}
+	if w.cfg.fsync {
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+	}
+	last.lastSeq = r.seq
+	last.lastTime = r.time
+	last.size += int64(len(frame))
+	return nil
+}
+
+// enforceRetention deletes the oldest segments exceeding the retention limits.
+// The last segment is never deleted. The caller must hold the lock, except while opening the log.
+func (w *wal) enforceRetention(now time.Time) {
+	var total int64
+	for _, seg := range w.segments {
+		total += seg.size
+	}
+	for len(w.segments) > 1 {
+		oldest := w.segments[0]
+		tooBig := w.cfg.maxBytes > 0 && total > w.cfg.maxBytes
+		tooOld := w.cfg.maxAge > 0 && now.Sub(oldest.lastTime) > w.cfg.maxAge
+		if !tooBig && !tooOld {
+			return
+		}
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		total -= oldest.size
+		w.segments = w.segments[1:]
+	}
+}
+
+// lastSeq returns the sequence number of the last record of the log, or zero if the log has always been empty.
+func (w *wal) lastSeq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.segments[len(w.segments)-1].lastSeq
+}
+
+// firstSeq returns the sequence number of the oldest record kept by the log.
+func (w *wal) firstSeq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.segments[0].firstSeq
+}
+
+// segmentFor returns the path and the size of the first segment containing records
+// whose sequence number is greater than or equal to seq, and whether it is the last segment.
+// It returns false if there is no such segment.
+func (w *wal) segmentFor(seq uint64) (seg walSegment, last bool, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, s := range w.segments {
+		if s.lastSeq >= seq && s.lastSeq >= s.firstSeq {
+			return *s, i == len(w.segments)-1, true
+		}
+	}
+	return walSegment{}, false, false
+}
+
+// sizeOf returns the current size of the valid records of the segment starting at firstSeq,
+// and whether it is the last segment.
+func (w *wal) sizeOf(firstSeq uint64) (int64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, s := range w.segments {
+		if s.firstSeq == firstSeq {
+			return s.size, i == len(w.segments)-1
+		}
+	}
+	// The segment has been deleted by the retention, its size can't change anymore.
+	return -1, false
+}
+
+// close closes the log.
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.file.Sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// walReader reads the records of a write-ahead log in order, starting from a sequence number.
+// It follows the records appended to the log after its creation.
+// It is not safe for concurrent use.
+type walReader struct {
+	w *wal
+	// seq is the sequence number of the next record to read.
+	seq  uint64
+	seg  walSegment
+	file *os.File
+	// offset is the position of the next record in the current segment.
+	offset int64
+}
+
+// reader returns a reader of the records whose sequence number is greater than or equal to seq.
+// If these records have been deleted by the retention, the reader starts from the oldest record.
+func (w *wal) reader(seq uint64) *walReader {
+	return &walReader{w: w, seq: seq}
+}
+
+// next returns the next record of the log, or nil if the reader has reached the end of the log.
+// The records whose sequence number is lower than the one requested are skipped.
+func (r *walReader) next() (*walRecord, error) {
+	for {
+		if r.file == nil {
+			seg, _, ok := r.w.segmentFor(r.seq)
+			if !ok {
+				return nil, nil
+			}
+			file, err := os.Open(seg.path)
+			if err != nil {
+				return nil, err
+			}
+			r.seg, r.file, r.offset = seg, file, 0
+		}
+
+		size, last := r.w.sizeOf(r.seg.firstSeq)
+		if size < 0 {
+			// The segment has been deleted, but the open file can still be read up to its known size.
+			size = r.seg.size
+		} else {
+			r.seg.size = size
+		}
+		if r.offset >= size {
+			if last {
+				return nil, nil
+			}
+			r.closeFile()
+			if r.seq <= r.seg.lastSeq {
+				r.seq = r.seg.lastSeq + 1
+			}
+			continue
+		}
+
+		rec, n, err := readFrame(r.file, r.offset, size)
+		if err != nil {
+			// Skip the rest of the segment.
+			r.offset = size
+			return nil, err
+		}
+		r.offset += n
+		r.seg.lastSeq = rec.seq
+		if rec.seq < r.seq {
+			continue
+		}
+		r.seq = rec.seq + 1
+		return rec, nil
+	}
+}
+
+// closeFile closes the segment file being read.
+func (r *walReader) closeFile() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+// close closes the reader.
+func (r *walReader) close() {
+	r.closeFile()
+}
+
+// offsetFile stores a sequence number in a file, along with its checksum.
+type offsetFile struct {
+	file  *os.File
+	fsync bool
+}
+
+// openOffset opens the offset file at path, creating it if needed,
+// and returns the sequence number it contains, or zero if it is new or corrupted.
+func openOffset(path string, fsync bool) (*offsetFile, uint64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+	var seq uint64
+	var buf [12]byte
+	if _, err := file.ReadAt(buf[:], 0); err == nil &&
+		crc32.Checksum(buf[:8], walCRCTable) == binary.BigEndian.Uint32(buf[8:]) {
+		seq = binary.BigEndian.Uint64(buf[:8])
+	}
+	return &offsetFile{file: file, fsync: fsync}, seq, nil
+}
+
+// store writes the sequence number to the file.
+func (o *offsetFile) store(seq uint64) error {
+	var buf [12]byte
+	binary.BigEndian.PutUint64(buf[:8], seq)
+	binary.BigEndian.PutUint32(buf[8:], crc32.Checksum(buf[:8], walCRCTable))
+	if _, err := o.file.WriteAt(buf[:], 0); err != nil {
+		return err
+	}
+	if o.fsync {
+		return o.file.Sync()
+	}
+	return nil
+}
+
+// close closes the file.
+func (o *offsetFile) close() error {
+	return o.file.Close()
+}
@@ -0,0 +1,225 @@
+package eventbus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readAll returns the records read from seq until the end of the log.
+func readAll(t *testing.T, w *wal, seq uint64) []*walRecord {
+	reader := w.reader(seq)
+	defer reader.close()
+	var records []*walRecord
+	for {
+		r, err := reader.next()
+		assert.Nil(t, err)
+		if r == nil {
+			return records
+		}
+		records = append(records, r)
+	}
+}
+
+func Test_WALRecord(t *testing.T) {
+	r := &walRecord{
+		seq:     42,
+		time:    time.Unix(0, 123456789),
+		id:      "id",
+		source:  "source",
+		headers: map[string]string{"k1": "v1", "k2": "v2"},
+		payload: []byte(`"payload"`),
+	}
+	frame := r.marshal()
+	decoded, err := unmarshalWALRecord(frame[walFrameHeaderSize:])
+	assert.Nil(t, err)
+	assert.Equal(t, r.seq, decoded.seq)
+	assert.True(t, r.time.Equal(decoded.time))
+	assert.Equal(t, r.id, decoded.id)
+	assert.Equal(t, r.source, decoded.source)
+	assert.Equal(t, r.headers, decoded.headers)
+	assert.Equal(t, r.payload, decoded.payload)
+
+	_, err = unmarshalWALRecord(frame[walFrameHeaderSize : walFrameHeaderSize+10])
+	assert.Equal(t, ErrCorruptedLog, err)
+	_, err = unmarshalWALRecord(frame[walFrameHeaderSize : walFrameHeaderSize+18])
+	assert.Equal(t, ErrCorruptedLog, err)
+}
+
+func Test_WALAppendAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL(dir, walConfig{segmentSize: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), w.lastSeq())
+	assert.Equal(t, uint64(1), w.firstSeq())
+
+	for i := 0; i < 10; i++ {
+		err = w.append(&walRecord{time: time.Now(), id: "id", payload: []byte{byte(i)}})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint64(10), w.lastSeq())
+	assert.Greater(t, len(w.segments), 1)
+
+	records := readAll(t, w, 4)
+	assert.Equal(t, 7, len(records))
+	for i, r := range records {
+		assert.Equal(t, uint64(i+4), r.seq)
+		assert.Equal(t, []byte{byte(i + 3)}, r.payload)
+	}
+	err = w.close()
+	assert.Nil(t, err)
+	err = w.append(&walRecord{})
+	assert.Equal(t, ErrChannelClosed, err)
+
+	w, err = openWAL(dir, walConfig{segmentSize: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), w.lastSeq())
+	err = w.append(&walRecord{time: time.Now()})
+	assert.Nil(t, err)
+	assert.Equal(t, 11, len(readAll(t, w, 0)))
+	w.close()
+}
+
+func Test_WALReaderFollows(t *testing.T) {
+	w, err := openWAL(t.TempDir(), walConfig{segmentSize: 50})
+	assert.Nil(t, err)
+	reader := w.reader(1)
+	defer reader.close()
+
+	r, err := reader.next()
+	assert.Nil(t, err)
+	assert.Nil(t, r)
+	for i := 1; i <= 20; i++ {
+		err = w.append(&walRecord{time: time.Now(), payload: []byte("payload")})
+		assert.Nil(t, err)
+		r, err = reader.next()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(i), r.seq)
+		r, err = reader.next()
+		assert.Nil(t, err)
+		assert.Nil(t, r)
+	}
+	w.close()
+}
+
+func Test_WALTruncatedTail(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL(dir, walConfig{segmentSize: 1 << 20})
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		err = w.append(&walRecord{time: time.Now(), payload: []byte("payload")})
+		assert.Nil(t, err)
+	}
+	path := w.segments[0].path
+	size := w.segments[0].size
+	w.close()
+
+	// Simulate a crash while writing the last record.
+	err = os.Truncate(path, size-3)
+	assert.Nil(t, err)
+	w, err = openWAL(dir, walConfig{segmentSize: 1 << 20})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), w.lastSeq())
+	err = w.append(&walRecord{time: time.Now(), payload: []byte("new")})
+	assert.Nil(t, err)
+	records := readAll(t, w, 0)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, uint64(3), records[2].seq)
+	assert.Equal(t, []byte("new"), records[2].payload)
+	w.close()
+}
+
+func Test_WALCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL(dir, walConfig{segmentSize: 1 << 20})
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		err = w.append(&walRecord{time: time.Now(), payload: []byte("payload")})
+		assert.Nil(t, err)
+	}
+	path := w.segments[0].path
+	w.close()
+
+	// Flip a byte of the payload of the second record.
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	data[len(data)*2/3-2] ^= 0xff
+	err = os.WriteFile(path, data, 0o644)
+	assert.Nil(t, err)
+
+	w, err = openWAL(dir, walConfig{segmentSize: 1 << 20})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), w.lastSeq())
+	assert.Equal(t, 1, len(readAll(t, w, 0)))
+	w.close()
+}
+
+func Test_WALRetention(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL(dir, walConfig{segmentSize: 100, maxBytes: 300})
+	assert.Nil(t, err)
+	for i := 0; i < 50; i++ {
+		err = w.append(&walRecord{time: time.Now(), payload: []byte("payload")})
+		assert.Nil(t, err)
+	}
+	var total int64
+	for _, seg := range w.segments {
+		total += seg.size
+	}
+	assert.LessOrEqual(t, total, int64(300+100))
+	assert.Greater(t, w.firstSeq(), uint64(1))
+	records := readAll(t, w, 1)
+	assert.Equal(t, w.firstSeq(), records[0].seq)
+	assert.Equal(t, uint64(50), records[len(records)-1].seq)
+	w.close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+walSegmentExt))
+	assert.Nil(t, err)
+	assert.Equal(t, len(w.segments), len(files))
+
+	// The segments older than the maximum age are deleted when the log is opened.
+	old := time.Now().Add(-time.Hour)
+	w, err = openWAL(t.TempDir(), walConfig{segmentSize: 1})
+	assert.Nil(t, err)
+	for i := 0; i < 5; i++ {
+		err = w.append(&walRecord{time: old, payload: []byte("payload")})
+		assert.Nil(t, err)
+	}
+	err = w.append(&walRecord{time: time.Now(), payload: []byte("payload")})
+	assert.Nil(t, err)
+	w.close()
+	w, err = openWAL(w.dir, walConfig{segmentSize: 1, maxAge: time.Minute})
+	assert.Nil(t, err)
+	records = readAll(t, w, 0)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, uint64(6), records[0].seq)
+	w.close()
+}
+
+func Test_OffsetFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.offset")
+	o, seq, err := openOffset(path, false)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), seq)
+	err = o.store(42)
+	assert.Nil(t, err)
+	o.close()
+
+	o, seq, err = openOffset(path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(42), seq)
+	err = o.store(43)
+	assert.Nil(t, err)
+	o.close()
+
+	// A corrupted offset is ignored.
+	err = os.WriteFile(path, []byte("corrupted offset"), 0o644)
+	assert.Nil(t, err)
+	o, seq, err = openOffset(path, false)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), seq)
+	o.close()
+}